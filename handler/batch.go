@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ellogroup/ello-golang-http/internal/recorder"
+	"github.com/ellogroup/ello-golang-http/middleware"
+	"github.com/ellogroup/ello-golang-http/query"
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// BatchRequest is a sub-request of a batch. The body is sent as JSON, with a Content-Type of application/json unless
+// another is set in the headers.
+type BatchRequest struct {
+	Method  string            `json:"method" validate:"required"`
+	Path    string            `json:"path" validate:"required,startswith=/"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse is the response to a sub-request of a batch. JSON response bodies are included as JSON, and any other
+// response body as a string.
+type BatchResponse struct {
+	RequestId string          `json:"request_id,omitempty"`
+	Status    int             `json:"status"`
+	Headers   http.Header     `json:"headers"`
+	Body      json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchOption configures the batch handler.
+type BatchOption func(*batch)
+
+// WithBatchConcurrency sets the number of sub-requests of a batch that are handled concurrently. By default,
+// sub-requests are handled one at a time, in order.
+func WithBatchConcurrency(n int) BatchOption {
+	return func(b *batch) {
+		b.concurrency = max(n, 1)
+	}
+}
+
+// WithBatchMaxRequests sets the maximum number of sub-requests in a batch, which is 20 by default.
+func WithBatchMaxRequests(n int) BatchOption {
+	return func(b *batch) {
+		b.maxRequests = n
+	}
+}
+
+// batchCtxKey marks the context of a sub-request of a batch.
+type batchCtxKey struct{}
+
+type batch struct {
+	router      http.Handler
+	concurrency int
+	maxRequests int
+}
+
+// NewBatch returns a handler function for a batch endpoint. The request body is a JSON array of BatchRequest, and each
+// sub-request is dispatched in-process through router, so it passes through the same middleware as any other request.
+// The response is a JSON array of BatchResponse, in the same order as the sub-requests.
+//
+// Sub-requests inherit the headers of the batch request (e.g. Authorization), other than Content-Type and
+// Content-Length, and any headers set on the sub-request take precedence. When the batch request has a request id, each
+// sub-request is given a request id derived from it (e.g. `abc-1`, `abc-2`), so it can be traced back to the batch.
+//
+// A 400 Bad Request error is returned if the body is malformed, has too many sub-requests or any sub-request is
+// invalid. A sub-request to the batch endpoint itself is invalid, and a sub-request that reaches any batch endpoint
+// (e.g. through another route) is given a 400 Bad Request error, so batches can't be nested.
+//
+// A sub-request that panics is given a 500 Internal Server Error response, and the panic is logged with
+// middleware.Log, so it doesn't fail the rest of the batch.
+func NewBatch(router http.Handler, opts ...BatchOption) func(w http.ResponseWriter, r *http.Request) {
+	b := &batch{
+		router:      router,
+		concurrency: 1,
+		maxRequests: 20,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return New(b.handle)
+}
+
+func (b *batch) handle(r *http.Request) response.Response {
+	if r.Context().Value(batchCtxKey{}) != nil {
+		return response.NewError(http.StatusBadRequest).WithMessage("Batch requests must not be nested").JsonResponse()
+	}
+
+	var reqs []BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		return response.AsErrorDetails(jsonDecodeError(err)).JsonResponse()
+	}
+	if b.maxRequests > 0 && len(reqs) > b.maxRequests {
+		return response.NewError(http.StatusBadRequest).
+			WithMessage(fmt.Sprintf("Batch must not have more than %d requests", b.maxRequests)).
+			JsonResponse()
+	}
+
+	subs := make([]*http.Request, len(reqs))
+	errs := map[string]error{}
+	for i, req := range reqs {
+		sub, err := b.newRequest(r, i, req)
+		if err != nil {
+			for name, err := range err {
+				errs[strings.TrimSuffix(fmt.Sprintf("[%d].%s", i, name), ".")] = err
+			}
+			continue
+		}
+		subs[i] = sub
+	}
+	if len(errs) > 0 {
+		return query.ErrorDetails(errs).JsonResponse()
+	}
+
+	resps := make([]BatchResponse, len(subs))
+	sem := make(chan struct{}, b.concurrency)
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			resps[i] = b.serve(sub)
+		}()
+	}
+	wg.Wait()
+
+	return response.NewJson(http.StatusOK, resps)
+}
+
+// newRequest creates the sub-request at index i of the batch, returning the validation errors if it is invalid.
+func (b *batch) newRequest(r *http.Request, i int, req BatchRequest) (*http.Request, map[string]error) {
+	if errs := validate.ValidateStruct(&req, "json"); len(errs) > 0 {
+		return nil, errs
+	}
+	if strings.HasPrefix(req.Path, "//") {
+		return nil, map[string]error{"path": fmt.Errorf("must be a path")}
+	}
+
+	// Clear the route context of the batch request, so the router routes the sub-request
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, (*chi.Context)(nil))
+	ctx = context.WithValue(ctx, batchCtxKey{}, true)
+	requestId := ""
+	if parentId := middleware.RequestId(ctx); parentId != "" {
+		requestId = fmt.Sprintf("%s-%d", parentId, i+1)
+		ctx = context.WithValue(ctx, middleware.RequestIDCtxKey, requestId)
+	}
+
+	sub, err := http.NewRequestWithContext(ctx, req.Method, req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, map[string]error{"": err}
+	}
+	if sub.URL.Path == r.URL.Path {
+		return nil, map[string]error{"path": fmt.Errorf("must not be the batch endpoint")}
+	}
+	sub.RequestURI = req.Path
+	sub.Proto, sub.ProtoMajor, sub.ProtoMinor = r.Proto, r.ProtoMajor, r.ProtoMinor
+	sub.Host = r.Host
+	sub.RemoteAddr = r.RemoteAddr
+	sub.TLS = r.TLS
+
+	sub.Header = r.Header.Clone()
+	sub.Header.Del("Content-Type")
+	sub.Header.Del("Content-Length")
+	sub.Header.Del(middleware.RequestIDHeader)
+	if requestId != "" {
+		sub.Header.Set(middleware.RequestIDHeader, requestId)
+	}
+	if len(req.Body) > 0 {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range req.Headers {
+		sub.Header.Set(k, v)
+	}
+	return sub, nil
+}
+
+func (b *batch) serve(r *http.Request) BatchResponse {
+	rec := b.record(r)
+
+	// The router may have its own request id middleware, which takes precedence
+	requestId := rec.HeaderMap.Get(middleware.RequestIDHeader)
+	if requestId == "" {
+		requestId = r.Header.Get(middleware.RequestIDHeader)
+	}
+	resp := BatchResponse{
+		RequestId: requestId,
+		Status:    rec.StatusCode,
+		Headers:   rec.HeaderMap,
+	}
+	if rec.Body.Len() == 0 {
+		return resp
+	}
+	mediaType, _, _ := mime.ParseMediaType(rec.HeaderMap.Get("Content-Type"))
+	if (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) && json.Valid(rec.Body.Bytes()) {
+		resp.Body = bytes.Clone(rec.Body.Bytes())
+	} else {
+		// Body is always valid JSON once encoded as a string
+		resp.Body, _ = json.Marshal(rec.Body.String())
+	}
+	return resp
+}
+
+// record dispatches the sub-request through the router, recording a 500 Internal Server Error response if it panics.
+func (b *batch) record(r *http.Request) (rec *recorder.ResponseRecorder) {
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			middleware.Log(r.Context(), zap.ErrorLevel, "Batch sub-request panicked",
+				zap.Any("panic", rvr), zap.String("method", r.Method), zap.String("path", r.URL.Path))
+			rec = recorder.New()
+			_ = response.NewError(http.StatusInternalServerError).JsonResponse().WriteTo(rec)
+		}
+	}()
+	rec = recorder.New()
+	b.router.ServeHTTP(rec, r)
+	return rec
+}
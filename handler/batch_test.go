@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ellogroup/ello-golang-http/middleware"
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newBatchRouter(opts ...BatchOption) http.Handler {
+	router := chi.NewRouter()
+	router.Use(middleware.NewRequestIdMiddleware())
+	router.Get("/users/{id}", New(func(r *http.Request) response.Response {
+		return response.NewJson(http.StatusOK, map[string]string{
+			"id":            chi.URLParam(r, "id"),
+			"request_id":    middleware.RequestId(r.Context()),
+			"authorization": r.Header.Get("Authorization"),
+			"query":         r.URL.Query().Get("q"),
+		})
+	}))
+	router.Post("/users", New(func(r *http.Request) response.Response {
+		body, _ := io.ReadAll(r.Body)
+		return response.NewJson(http.StatusCreated, map[string]string{
+			"content_type": r.Header.Get("Content-Type"),
+			"body":         string(body),
+		})
+	}))
+	router.Get("/text", New(func(r *http.Request) response.Response {
+		return response.New(http.StatusOK, []byte("hello")).WithHeader("Content-Type", "text/plain")
+	}))
+	router.Get("/panic", func(http.ResponseWriter, *http.Request) {
+		panic("sub-request failed")
+	})
+	router.Post("/batch", NewBatch(router, opts...))
+	router.Post("/other-batch", NewBatch(router, opts...))
+	return router
+}
+
+func TestNewBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []BatchOption
+		body       string
+		wantStatus int
+		wantBody   string
+		wantMeta   map[string]string
+	}{
+		{
+			name: "Sub-requests are dispatched through the router",
+			body: `[{"method":"GET","path":"/users/1?q=a"},` +
+				`{"method":"POST","path":"/users","body":{"name":"Test"}},` +
+				`{"method":"GET","path":"/users/2","headers":{"Authorization":"Bearer other"}},` +
+				`{"method":"GET","path":"/text"},` +
+				`{"method":"DELETE","path":"/users/1"}]`,
+			wantStatus: http.StatusOK,
+			wantBody: `[
+				{"request_id":"parent-1","status":200,"body":{"id":"1","request_id":"parent-1",
+					"authorization":"Bearer token","query":"a"}},
+				{"request_id":"parent-2","status":201,"body":{"content_type":"application/json",
+					"body":"{\"name\":\"Test\"}"}},
+				{"request_id":"parent-3","status":200,"body":{"id":"2","request_id":"parent-3",
+					"authorization":"Bearer other","query":""}},
+				{"request_id":"parent-4","status":200,"body":"hello"},
+				{"request_id":"parent-5","status":405}
+			]`,
+		},
+		{
+			name:       "Panicking sub-requests",
+			body:       `[{"method":"GET","path":"/panic"},{"method":"GET","path":"/text"}]`,
+			wantStatus: http.StatusOK,
+			wantBody: `[
+				{"request_id":"parent-1","status":500,"body":{"error":{"status":500,
+					"code":"internal_server_error","message":"Internal Server Error","meta":null}}},
+				{"request_id":"parent-2","status":200,"body":"hello"}
+			]`,
+		},
+		{
+			name:       "Nested batches",
+			body:       `[{"method":"POST","path":"/other-batch","body":[{"method":"GET","path":"/text"}]}]`,
+			wantStatus: http.StatusOK,
+			wantBody: `[
+				{"request_id":"parent-1","status":400,"body":{"error":{"status":400,"code":"bad_request",
+					"message":"Batch requests must not be nested","meta":null}}}
+			]`,
+		},
+		{
+			name:       "Empty batch",
+			body:       `[]`,
+			wantStatus: http.StatusOK,
+			wantBody:   `[]`,
+		},
+		{
+			name:       "Malformed body",
+			body:       `{"method":"GET"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Too many sub-requests",
+			opts:       []BatchOption{WithBatchMaxRequests(3)},
+			body:       `[` + strings.Repeat(`{"method":"GET","path":"/text"},`, 3) + `{"method":"GET","path":"/text"}]`,
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"error":{"status":400,"code":"bad_request",` +
+				`"message":"Batch must not have more than 3 requests","meta":null}}`,
+		},
+		{
+			name: "Invalid sub-requests",
+			body: `[{"method":"GET","path":"/text"},{"path":"users"},{"method":"GET","path":"//example.com/"},` +
+				`{"method":"POST","path":"/batch"},{"method":"BAD METHOD","path":"/text"}]`,
+			wantStatus: http.StatusBadRequest,
+			wantMeta: map[string]string{
+				"[1].method": "failed on the 'required' rule",
+				"[1].path":   "failed on the 'startswith=/' rule",
+				"[2].path":   "must be a path",
+				"[3].path":   "must not be the batch endpoint",
+				"[4]":        `net/http: invalid method "BAD METHOD"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "Bearer token")
+			r.Header.Set(middleware.RequestIDHeader, "parent")
+
+			rec := httptest.NewRecorder()
+			newBatchRouter(tt.opts...).ServeHTTP(rec, r)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantMeta != nil {
+				var got struct {
+					Error struct {
+						Meta map[string]string `json:"meta"`
+					} `json:"error"`
+				}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+				assert.Equal(t, tt.wantMeta, got.Error.Meta)
+			}
+			if tt.wantBody == "" {
+				return
+			}
+			if tt.wantStatus != http.StatusOK {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+				return
+			}
+			// Compare without the sub-response headers
+			var got []map[string]any
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			for _, resp := range got {
+				delete(resp, "headers")
+			}
+			b, _ := json.Marshal(got)
+			assert.JSONEq(t, tt.wantBody, string(b))
+		})
+	}
+}
+
+func TestNewBatch_TrustedProxies(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(middleware.NewRequestIdMiddleware(
+		middleware.WithRequestIdTrustedProxies(netip.MustParsePrefix("10.0.0.0/8")),
+		middleware.WithRequestIdMaxLength(36),
+	))
+	router.Get("/text", New(func(r *http.Request) response.Response {
+		return response.New(http.StatusOK, []byte(middleware.RequestId(r.Context())))
+	}))
+	router.Post("/batch", NewBatch(router))
+
+	// The request id of an untrusted client is replaced, and the sub-requests derive theirs from the replacement,
+	// even though they are longer than the maximum length
+	r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`[{"method":"GET","path":"/text"}]`))
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set(middleware.RequestIDHeader, "untrusted")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	parentId := rec.Header().Get(middleware.RequestIDHeader)
+	assert.NotEqual(t, "untrusted", parentId)
+	var got []BatchResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	if assert.Len(t, got, 1) {
+		assert.Equal(t, parentId+"-1", got[0].RequestId)
+		assert.JSONEq(t, `"`+parentId+`-1"`, string(got[0].Body))
+	}
+}
+
+func TestNewBatch_PanicLogged(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`[{"method":"GET","path":"/panic"}]`))
+	r = r.WithContext(context.WithValue(r.Context(), middleware.LoggerCtxKey, zap.New(core)))
+
+	rec := httptest.NewRecorder()
+	newBatchRouter().ServeHTTP(rec, r)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Equal(t, 1, logs.Len()) {
+		entry := logs.All()[0]
+		assert.Equal(t, "Batch sub-request panicked", entry.Message)
+		assert.Equal(t, map[string]any{"panic": "sub-request failed", "method": "GET", "path": "/panic"},
+			entry.ContextMap())
+	}
+}
+
+func TestNewBatch_Concurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	router := chi.NewRouter()
+	router.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	router.Post("/batch", NewBatch(router, WithBatchConcurrency(2)))
+
+	body := `[` + strings.Repeat(`{"method":"GET","path":"/slow"},`, 5) + `{"method":"GET","path":"/slow"}]`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var got []BatchResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Len(t, got, 6)
+	for _, resp := range got {
+		assert.Equal(t, http.StatusNoContent, resp.Status)
+		assert.Empty(t, resp.RequestId, "no request id without a parent request id")
+	}
+	assert.Equal(t, int32(2), maxRunning.Load())
+}
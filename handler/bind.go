@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-http/query"
+	"github.com/ellogroup/ello-golang-http/response"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// bindSources are the struct tags read by Bind, in the order they are decoded, and the source name used to qualify
+// errors. Later sources take precedence if a field has more than one tag.
+var bindSources = []struct{ tag, source string }{
+	{"json", "body"},
+	{"path", "path"},
+	{"query", "query"},
+	{"header", "header"},
+	{"cookie", "cookie"},
+}
+
+// Bind fills dst, which must be a pointer to a struct, from every part of the request, and then validates it once
+// using `validate` struct tags. Each field is read from the source named by its struct tag:
+//
+//   - `path:"id"` from a chi path parameter
+//   - `query:"limit"` from a query parameter
+//   - `header:"X-Tenant-Id"` from a request header
+//   - `cookie:"session"` from a cookie
+//   - `json:"items"` from the JSON request body
+//
+// Fields with a `default:"10"` tag are set to the default value when it is not provided by the request. Only fields
+// with a `json` tag are read from the body, so the body can not override values from other sources.
+//
+// The returned error will be response.ErrorDetails holding every problem with the request in the meta, keyed by the
+// source and name of the field (e.g. "query.limit" or "body.items[2].sku"): 400 Bad Request if any value could not be
+// decoded, or 422 Unprocessable Entity if all values were decoded but failed validation. 415 Unsupported Media Type is
+// returned if the struct has `json` tags and a request body with a Content-Type other than application/json.
+func Bind(r *http.Request, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind destination must be a pointer to a struct, got %T", dst)
+	}
+	rt := rv.Elem().Type()
+
+	if err := applyDefaults(rv.Elem()); err != nil {
+		return err
+	}
+
+	errs := map[string]error{}
+	for _, s := range bindSources {
+		names := tagNames(rt, s.tag)
+		if len(names) == 0 {
+			continue
+		}
+
+		var values url.Values
+		switch s.tag {
+		case "json":
+			if err := bindJsonBody(r, rv.Elem(), errs); err != nil {
+				return err
+			}
+			continue
+		case "path":
+			values = pathValues(r)
+		case "query":
+			values = r.URL.Query()
+		case "header":
+			values = headerValues(r, names)
+		case "cookie":
+			values = cookieValues(r, names)
+		}
+		for name, err := range query.Decode(values, dst, s.tag) {
+			errs[s.source+"."+name] = err
+		}
+	}
+
+	statusCode := http.StatusUnprocessableEntity
+	if len(errs) > 0 {
+		statusCode = http.StatusBadRequest
+	}
+
+	// Validate without a tag so errors are keyed by the namespace of the field, which is then qualified by source
+	for ns, err := range validate.ValidateStruct(dst, "") {
+		if ns == "" {
+			return err
+		}
+		name := bindErrorName(rt, ns)
+		if _, exists := errs[name]; !exists {
+			errs[name] = err
+		}
+	}
+
+	if len(errs) > 0 {
+		return query.ErrorDetailsWithStatus(statusCode, errs)
+	}
+	return nil
+}
+
+// bindJsonBody decodes the JSON request body into the fields of rv with a `json` tag. An empty body is ignored. Type
+// errors are added to errs, any other error is returned.
+func bindJsonBody(r *http.Request, rv reflect.Value, errs map[string]error) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "application/json" {
+			return response.NewError(http.StatusUnsupportedMediaType)
+		}
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return jsonDecodeError(err)
+	}
+
+	// Decode into a copy, so only fields with a json tag are copied back to the destination
+	body := reflect.New(rv.Type())
+	body.Elem().Set(rv)
+	err = json.NewDecoder(bytes.NewReader(data)).Decode(body.Interface())
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return nil
+	case errors.As(err, &typeErr):
+		path := jsonPathAt(data, typeErr.Offset)
+		if path == "" {
+			return jsonDecodeError(err)
+		}
+		errs["body."+path] = fmt.Errorf("must be of type %s", typeErr.Type)
+	case err != nil:
+		return jsonDecodeError(err)
+	}
+
+	walkFields(body.Elem(), func(field reflect.StructField, v reflect.Value) {
+		if query.TagName(field, "json") != "" {
+			rv.FieldByIndex(field.Index).Set(v)
+		}
+	})
+	return nil
+}
+
+// jsonPathFrame is an object or array being walked by jsonPathAt.
+type jsonPathFrame struct {
+	array   bool
+	index   int
+	key     string
+	wantKey bool
+}
+
+// jsonPathAt returns the path of the JSON value in data ending at offset, the offset of a JSON type error, in the same
+// format as validation errors (e.g. "items[2].sku"). The path is found by walking the tokens of data, as not every
+// version of encoding/json includes array indices in the field of a type error. An empty string is returned for the
+// top-level value, or if the offset is not found.
+func jsonPathAt(data []byte, offset int64) string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var stack []*jsonPathFrame
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		var top *jsonPathFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		delim, isDelim := tok.(json.Delim)
+
+		// Object keys and the end of objects and arrays
+		switch {
+		case top != nil && top.wantKey && isDelim, isDelim && delim == ']':
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 {
+				stack[len(stack)-1].wantKey = !stack[len(stack)-1].array
+			}
+			continue
+		case top != nil && top.wantKey:
+			top.key, top.wantKey = tok.(string), false
+			continue
+		}
+
+		// The start of a value
+		if top != nil && top.array {
+			top.index++
+		}
+		if dec.InputOffset() >= offset {
+			return jsonPath(stack)
+		}
+		switch {
+		case isDelim && delim == '{':
+			stack = append(stack, &jsonPathFrame{wantKey: true})
+		case isDelim && delim == '[':
+			stack = append(stack, &jsonPathFrame{array: true, index: -1})
+		case top != nil:
+			top.wantKey = !top.array
+		}
+	}
+}
+
+func jsonPath(stack []*jsonPathFrame) string {
+	var b strings.Builder
+	for i, frame := range stack {
+		switch {
+		case frame.array:
+			b.WriteString("[" + strconv.Itoa(frame.index) + "]")
+		case i > 0:
+			b.WriteString("." + frame.key)
+		default:
+			b.WriteString(frame.key)
+		}
+	}
+	return b.String()
+}
+
+// applyDefaults sets each field with a `default` tag to the default value, if the field is the zero value. Slices are
+// set from a comma-separated list.
+func applyDefaults(rv reflect.Value) error {
+	var err error
+	walkFields(rv, func(field reflect.StructField, v reflect.Value) {
+		def, ok := field.Tag.Lookup("default")
+		if !ok || err != nil || !v.IsZero() {
+			return
+		}
+		values := []string{def}
+		if v.Kind() == reflect.Slice {
+			values = strings.Split(def, ",")
+		}
+		if decodeErr := query.DecodeValue(v, values); decodeErr != nil {
+			err = fmt.Errorf("invalid default value for field %s: %w", field.Name, decodeErr)
+		}
+	})
+	return err
+}
+
+func headerValues(r *http.Request, names []string) url.Values {
+	values := url.Values{}
+	for _, name := range names {
+		if v := r.Header.Values(name); len(v) > 0 {
+			values[name] = v
+		}
+	}
+	return values
+}
+
+func cookieValues(r *http.Request, names []string) url.Values {
+	values := url.Values{}
+	for _, name := range names {
+		if c, err := r.Cookie(name); err == nil {
+			values.Set(name, c.Value)
+		}
+	}
+	return values
+}
+
+// bindErrorName converts the namespace of a struct field (e.g. "Items[2].Sku") into the name of the field qualified
+// by its source (e.g. "body.items[2].sku"). The namespace is returned unchanged if it can not be resolved.
+func bindErrorName(rt reflect.Type, ns string) string {
+	var source string
+	var names []string
+	for _, segment := range strings.Split(ns, ".") {
+		fieldName, index, hasIndex := strings.Cut(segment, "[")
+		for rt.Kind() == reflect.Pointer || rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array ||
+			rt.Kind() == reflect.Map {
+			rt = rt.Elem()
+		}
+		if rt.Kind() != reflect.Struct {
+			return ns
+		}
+		field, ok := rt.FieldByName(fieldName)
+		if !ok {
+			return ns
+		}
+		rt = field.Type
+		if field.Anonymous {
+			continue
+		}
+
+		name := field.Name
+		if source == "" {
+			source, name = bindSource(field)
+		} else if tagName := query.TagName(field, "json"); tagName != "" {
+			name = tagName
+		}
+		if hasIndex {
+			name += "[" + index
+		}
+		names = append(names, name)
+	}
+	if source == "" {
+		return strings.Join(names, ".")
+	}
+	return source + "." + strings.Join(names, ".")
+}
+
+// bindSource returns the source of a top-level field and its name within that source.
+func bindSource(field reflect.StructField) (string, string) {
+	for i := len(bindSources) - 1; i >= 0; i-- {
+		if name := query.TagName(field, bindSources[i].tag); name != "" {
+			return bindSources[i].source, name
+		}
+	}
+	return "", field.Name
+}
+
+// tagNames returns the names in the given tag of the fields of a struct, including fields of embedded structs.
+func tagNames(rt reflect.Type, tag string) []string {
+	var names []string
+	walkFields(reflect.New(rt).Elem(), func(field reflect.StructField, _ reflect.Value) {
+		if name := query.TagName(field, tag); name != "" {
+			names = append(names, name)
+		}
+	})
+	return names
+}
+
+// walkFields calls fn for each exported field of a struct, including fields of embedded structs. The index of each
+// field is relative to the struct rv.
+func walkFields(rv reflect.Value, fn func(field reflect.StructField, v reflect.Value)) {
+	walkFieldsIndex(rv, nil, fn)
+}
+
+func walkFieldsIndex(rv reflect.Value, index []int, fn func(field reflect.StructField, v reflect.Value)) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		field.Index = append(append([]int{}, index...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			walkFieldsIndex(rv.Field(i), field.Index, fn)
+			continue
+		}
+		if field.IsExported() {
+			fn(field, rv.Field(i))
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bindPaging struct {
+	Limit int `query:"limit" default:"20" validate:"min=1,max=100"`
+}
+
+type bindItem struct {
+	Sku      string `json:"sku" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1"`
+}
+
+type bindTarget struct {
+	bindPaging
+	OrderId  int        `path:"orderId" validate:"min=1"`
+	Tags     []string   `query:"tag" default:"a,b"`
+	TenantId string     `header:"X-Tenant-Id" validate:"required"`
+	Session  string     `cookie:"session"`
+	Note     string     `json:"note" default:"none"`
+	Items    []bindItem `json:"items" validate:"required,dive"`
+	Internal string
+}
+
+func newBindRequest(url, body string, params map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	r.Header.Set("X-Tenant-Id", "tenant")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	return withPathParams(r, params)
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    func() *http.Request
+		want       bindTarget
+		wantStatus int
+		wantMeta   map[string]string
+	}{
+		{
+			name: "All sources are bound",
+			request: func() *http.Request {
+				return newBindRequest("/orders/7?limit=5&tag=x&tag=y",
+					`{"note":"hello","items":[{"sku":"A1","quantity":2}],"Internal":"ignored"}`,
+					map[string]string{"orderId": "7"})
+			},
+			want: bindTarget{
+				bindPaging: bindPaging{Limit: 5},
+				OrderId:    7,
+				Tags:       []string{"x", "y"},
+				TenantId:   "tenant",
+				Session:    "abc",
+				Note:       "hello",
+				Items:      []bindItem{{Sku: "A1", Quantity: 2}},
+			},
+		},
+		{
+			name: "Defaults are applied when not provided",
+			request: func() *http.Request {
+				return newBindRequest("/orders/7", `{"items":[{"sku":"A1","quantity":1}]}`,
+					map[string]string{"orderId": "7"})
+			},
+			want: bindTarget{
+				bindPaging: bindPaging{Limit: 20},
+				OrderId:    7,
+				Tags:       []string{"a", "b"},
+				TenantId:   "tenant",
+				Session:    "abc",
+				Note:       "none",
+				Items:      []bindItem{{Sku: "A1", Quantity: 1}},
+			},
+		},
+		{
+			name: "Validation errors are qualified by source",
+			request: func() *http.Request {
+				r := newBindRequest("/orders/0?limit=1000", `{"items":[{"sku":"A1","quantity":1},{"quantity":0}]}`,
+					map[string]string{"orderId": "0"})
+				r.Header.Del("X-Tenant-Id")
+				return r
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantMeta: map[string]string{
+				"query.limit":            "failed on the 'max=100' rule",
+				"path.orderId":           "failed on the 'min=1' rule",
+				"header.X-Tenant-Id":     "failed on the 'required' rule",
+				"body.items[1].sku":      "failed on the 'required' rule",
+				"body.items[1].quantity": "failed on the 'min=1' rule",
+			},
+		},
+		{
+			name: "Decode errors return bad request with validation errors",
+			request: func() *http.Request {
+				return newBindRequest("/orders/x?limit=ten", `{"items":[]}`, map[string]string{"orderId": "x"})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantMeta: map[string]string{
+				"query.limit":  `invalid integer "ten"`,
+				"path.orderId": `invalid integer "x"`,
+			},
+		},
+		{
+			name: "Body type errors return bad request",
+			request: func() *http.Request {
+				return newBindRequest("/orders/7", `{"items":[{"sku":1,"quantity":1}]}`, map[string]string{"orderId": "7"})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantMeta: map[string]string{
+				"body.items[0].sku": "must be of type string",
+			},
+		},
+		{
+			name: "Missing body fails validation",
+			request: func() *http.Request {
+				return newBindRequest("/orders/7", "", map[string]string{"orderId": "7"})
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantMeta: map[string]string{
+				"body.items": "failed on the 'required' rule",
+			},
+		},
+		{
+			name: "Malformed body",
+			request: func() *http.Request {
+				return newBindRequest("/orders/7", `{"items":`, map[string]string{"orderId": "7"})
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Body with unsupported content type",
+			request: func() *http.Request {
+				r := newBindRequest("/orders/7", "items=1", map[string]string{"orderId": "7"})
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return r
+			},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bindTarget
+			err := Bind(tt.request(), &got)
+			if tt.wantStatus == 0 {
+				assert.NoError(t, err)
+				assert.Equalf(t, tt.want, got, "Bind()")
+				return
+			}
+			details := response.AsErrorDetails(err)
+			assert.Equalf(t, tt.wantStatus, details.Status, "status (%v)", err)
+			if tt.wantMeta != nil {
+				assert.Equalf(t, tt.wantMeta, details.Meta, "meta")
+			}
+		})
+	}
+}
+
+func TestBind_InvalidDestination(t *testing.T) {
+	r := newBindRequest("/", "", nil)
+
+	var target bindTarget
+	assert.Error(t, Bind(r, target))
+	assert.Error(t, Bind(r, (*bindTarget)(nil)))
+
+	var invalidDefault struct {
+		Limit int `query:"limit" default:"ten"`
+	}
+	assert.ErrorContains(t, Bind(r, &invalidDefault), "invalid default value for field Limit")
+}
+
+func Test_jsonPathAt(t *testing.T) {
+	type item struct {
+		Sku  string   `json:"sku"`
+		Tags []string `json:"tags"`
+	}
+	type body struct {
+		Note  string          `json:"note"`
+		Items []item          `json:"items"`
+		Grid  [][]int         `json:"grid"`
+		Meta  map[string]item `json:"meta"`
+	}
+	tests := map[string]string{
+		`{"note":1}`: "note",
+		`{"note":"a","items":[{"sku":"A"},{"tags":[]},{"sku":{"a":[1]}}]}`: "items[2].sku",
+		`{"items":[{"sku":"A","tags":["a",2]}]}`:                           "items[0].tags[1]",
+		`{"grid":[[1],[2,"x"]]}`:                                           "grid[1][1]",
+		`{"meta":{"a":{},"b":{"sku":true}}}`:                               "meta.b.sku",
+		` { "items" : [ { } , { "sku" : [ ] } ] }`:                         "items[1].sku",
+		`{"items":{}}`: "items",
+	}
+	for data, want := range tests {
+		var typeErr *json.UnmarshalTypeError
+		if assert.ErrorAsf(t, json.Unmarshal([]byte(data), &body{}), &typeErr, "json.Unmarshal(%v)", data) {
+			assert.Equalf(t, want, jsonPathAt([]byte(data), typeErr.Offset), "jsonPathAt(%v)", data)
+		}
+	}
+	assert.Equal(t, "", jsonPathAt([]byte(`[1]`), 1), "top-level value")
+}
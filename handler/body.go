@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-http/query"
+	"github.com/ellogroup/ello-golang-http/response"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// BindBody decodes the request body into dst, which must be a pointer to a struct, and validates it using `validate`
+// struct tags. The body is decoded according to the Content-Type of the request: `json` struct tags are used for
+// application/json and `form` struct tags for application/x-www-form-urlencoded, so the same struct can accept either.
+//
+// The returned error will be response.ErrorDetails when the request is invalid: 415 Unsupported Media Type for any
+// other Content-Type, 413 Request Entity Too Large if the body exceeds a limit set with http.MaxBytesReader, or 400
+// Bad Request if the body is malformed or fails validation. The meta of the error holds the details of each invalid
+// field.
+func BindBody(r *http.Request, dst any) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		return BindJson(r, dst)
+	case "application/x-www-form-urlencoded":
+		return BindForm(r, dst)
+	}
+	return response.NewError(http.StatusUnsupportedMediaType)
+}
+
+// BindJson decodes a JSON request body into dst, which must be a pointer to a struct, and validates it using `validate`
+// struct tags. Validation errors are keyed by the `json` tag name of the field.
+func BindJson(r *http.Request, dst any) error {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		return jsonDecodeError(err)
+	}
+	return validateBody(dst, "json")
+}
+
+// BindForm decodes a URL-encoded form request body into dst, which must be a pointer to a struct, using `form` struct
+// tags, and validates it using `validate` struct tags. Query parameters are not included. Errors are keyed by the
+// `form` tag name of the field.
+func BindForm(r *http.Request, dst any) error {
+	if err := r.ParseForm(); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return response.NewError(http.StatusRequestEntityTooLarge)
+		}
+		return response.NewError(http.StatusBadRequest).WithMessage("Malformed form body")
+	}
+	errs := validate.BindTag(r.PostForm, dst, "form")
+	if err, ok := errs[""]; ok {
+		return err
+	}
+	if len(errs) > 0 {
+		return query.ErrorDetails(errs)
+	}
+	return nil
+}
+
+func validateBody(dst any, tag string) error {
+	errs := validate.ValidateStruct(dst, tag)
+	if err, ok := errs[""]; ok {
+		return err
+	}
+	if len(errs) > 0 {
+		return query.ErrorDetails(errs)
+	}
+	return nil
+}
+
+func jsonDecodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	var invalidErr *json.InvalidUnmarshalError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return response.NewError(http.StatusRequestEntityTooLarge)
+	case errors.Is(err, io.EOF):
+		return response.NewError(http.StatusBadRequest).WithMessage("Request body is empty")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return response.NewError(http.StatusBadRequest).
+			WithMessage("Invalid JSON body").
+			WithMeta(map[string]string{typeErr.Field: fmt.Sprintf("must be of type %s", typeErr.Type)})
+	case errors.As(err, &invalidErr):
+		return err
+	}
+	return response.NewError(http.StatusBadRequest).WithMessage("Malformed JSON body")
+}
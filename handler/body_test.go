@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bodyTarget struct {
+	Name  string   `json:"name" form:"name" validate:"required"`
+	Count int      `json:"count" form:"count" validate:"omitempty,max=10"`
+	Tags  []string `json:"tags" form:"tag"`
+}
+
+func newBodyRequest(contentType, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/items?name=query", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	return r
+}
+
+func TestBindBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    *http.Request
+		want       bodyTarget
+		wantStatus int
+		wantMeta   map[string]string
+	}{
+		{
+			name:    "JSON body is decoded",
+			request: newBodyRequest("application/json", `{"name":"test","count":2,"tags":["a","b"]}`),
+			want:    bodyTarget{Name: "test", Count: 2, Tags: []string{"a", "b"}},
+		},
+		{
+			name:    "JSON body with charset is decoded",
+			request: newBodyRequest("application/json; charset=utf-8", `{"name":"test"}`),
+			want:    bodyTarget{Name: "test"},
+		},
+		{
+			name:    "Form body is decoded",
+			request: newBodyRequest("application/x-www-form-urlencoded", "name=test&count=2&tag=a&tag=b"),
+			want:    bodyTarget{Name: "test", Count: 2, Tags: []string{"a", "b"}},
+		},
+		{
+			name:       "Form body does not include query parameters",
+			request:    newBodyRequest("application/x-www-form-urlencoded", "count=2"),
+			wantStatus: http.StatusBadRequest,
+			wantMeta:   map[string]string{"name": "failed on the 'required' rule"},
+		},
+		{
+			name:       "Unsupported content type",
+			request:    newBodyRequest("text/plain", "name=test"),
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "Missing content type",
+			request:    newBodyRequest("", `{"name":"test"}`),
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "Invalid JSON body fails validation",
+			request:    newBodyRequest("application/json", `{"count":11}`),
+			wantStatus: http.StatusBadRequest,
+			wantMeta: map[string]string{
+				"name":  "failed on the 'required' rule",
+				"count": "failed on the 'max=10' rule",
+			},
+		},
+		{
+			name:       "Invalid form body fails validation",
+			request:    newBodyRequest("application/x-www-form-urlencoded", "name=test&count=11"),
+			wantStatus: http.StatusBadRequest,
+			wantMeta:   map[string]string{"count": "failed on the 'max=10' rule"},
+		},
+		{
+			name:       "Form value of wrong type",
+			request:    newBodyRequest("application/x-www-form-urlencoded", "name=test&count=two"),
+			wantStatus: http.StatusBadRequest,
+			wantMeta:   map[string]string{"count": `invalid integer "two"`},
+		},
+		{
+			name:       "JSON value of wrong type",
+			request:    newBodyRequest("application/json", `{"name":"test","count":"two"}`),
+			wantStatus: http.StatusBadRequest,
+			wantMeta:   map[string]string{"count": "must be of type int"},
+		},
+		{
+			name:       "Malformed JSON body",
+			request:    newBodyRequest("application/json", `{"name":`),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Empty JSON body",
+			request:    newBodyRequest("application/json", ""),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Malformed form body",
+			request:    newBodyRequest("application/x-www-form-urlencoded", "name=%zz"),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bodyTarget
+			err := BindBody(tt.request, &got)
+			if tt.wantStatus == 0 {
+				assert.NoError(t, err)
+				assert.Equalf(t, tt.want, got, "BindBody()")
+				return
+			}
+			details := response.AsErrorDetails(err)
+			assert.Equalf(t, tt.wantStatus, details.Status, "status (%v)", err)
+			if tt.wantMeta != nil {
+				assert.Equalf(t, tt.wantMeta, details.Meta, "meta")
+			}
+		})
+	}
+}
+
+func TestBindBody_TooLarge(t *testing.T) {
+	for _, contentType := range []string{"application/json", "application/x-www-form-urlencoded"} {
+		r := newBodyRequest(contentType, `{"name":"`+strings.Repeat("a", 100)+`"}`)
+		r.Body = http.MaxBytesReader(nil, r.Body, 10)
+
+		var got bodyTarget
+		err := BindBody(r, &got)
+		assert.Equalf(t, http.StatusRequestEntityTooLarge, response.AsErrorDetails(err).Status, "%s (%v)", contentType, err)
+	}
+}
+
+func TestBindBody_InvalidDestination(t *testing.T) {
+	for _, contentType := range []string{"application/json", "application/x-www-form-urlencoded"} {
+		var got bodyTarget
+		err := BindBody(newBodyRequest(contentType, `{}`), got)
+		assert.Errorf(t, err, contentType)
+		assert.Equalf(t, http.StatusInternalServerError, response.AsErrorDetails(err).Status, contentType)
+	}
+}
@@ -3,20 +3,48 @@ package handler
 import (
 	"github.com/ellogroup/ello-golang-http/middleware"
 	"github.com/ellogroup/ello-golang-http/response"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"net/http"
 )
 
+// HandlerOption configures the handler returned by New.
+type HandlerOption func(*handlerConfig)
+
+// WithHandlerLogger sets how write failures are logged, e.g. middleware.ZapLogFunc(log) or
+// middleware.SlogLogFunc(log) to log to a specific logger with the logctx fields of the request. By default,
+// middleware.Log is used, logging through whichever logger is configured in the request context.
+func WithHandlerLogger(log middleware.LogFunc) HandlerOption {
+	return func(c *handlerConfig) {
+		c.log = log
+	}
+}
+
+type handlerConfig struct {
+	log middleware.LogFunc
+}
+
 // New converts a function that takes a request and returns a response, and returns a new handler function that
 // implements the http.Handler interface for a http server. This wrapper will pass the request to the handler, and then
-// write the response to the response writer.
-func New(handler func(r *http.Request) response.Response) func(w http.ResponseWriter, r *http.Request) {
+// write the response to the response writer. Error responses and write failures are recorded on the OpenTelemetry span
+// of the request, if any, and write failures are logged with middleware.Log unless set by WithHandlerLogger.
+func New(
+	handler func(r *http.Request) response.Response,
+	opts ...HandlerOption,
+) func(w http.ResponseWriter, r *http.Request) {
+	c := &handlerConfig{log: middleware.Log}
+	for _, opt := range opts {
+		opt(c)
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		resp := handler(r)
+		if body, ok := resp.BodyDecoded.(response.ErrorBody); ok {
+			middleware.RecordErrorResponse(r.Context(), body.ErrorDetails)
+		}
 		if err := resp.WriteTo(w); err != nil {
 			// Unable to write the response to the response writer
-			log := middleware.Logger(r.Context())
-			log.Error("Unable to write response", zap.Error(err))
+			trace.SpanFromContext(r.Context()).RecordError(err)
+			c.log(r.Context(), zap.ErrorLevel, "Unable to write response", zap.Error(err))
 		}
 	}
 }
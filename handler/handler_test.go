@@ -1,13 +1,23 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"github.com/ellogroup/ello-golang-ctx/logctx"
 	"github.com/ellogroup/ello-golang-http/internal/mock"
 	"github.com/ellogroup/ello-golang-http/middleware"
 	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -58,3 +68,119 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestNew_SlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	writerMock := new(mock.ResponseWriter)
+	writerMock.On("Header").Return(http.Header{})
+	writerMock.On("WriteHeader", http.StatusOK).Once()
+	writerMock.On("Write", []byte("test body")).Return(0, errors.New("could not write")).Once()
+
+	handler := New(func(*http.Request) response.Response {
+		return response.New(http.StatusOK, []byte("test body"))
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), middleware.SlogLoggerCtxKey, log))
+	handler(writerMock, r)
+
+	writerMock.AssertExpectations(t)
+	var entry map[string]any
+	if assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry)) {
+		assert.Equal(t, "ERROR", entry["level"])
+		assert.Equal(t, "Unable to write response", entry["msg"])
+		assert.Equal(t, "could not write", entry["error"])
+	}
+}
+
+func TestNew_Logger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	writerMock := new(mock.ResponseWriter)
+	writerMock.On("Header").Return(http.Header{})
+	writerMock.On("WriteHeader", http.StatusOK).Once()
+	writerMock.On("Write", []byte("test body")).Return(0, errors.New("could not write")).Once()
+
+	handler := New(func(*http.Request) response.Response {
+		return response.New(http.StatusOK, []byte("test body"))
+	}, WithHandlerLogger(middleware.ZapLogFunc(zap.New(core))))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(logctx.Add(r.Context(), logctx.String("request_id", "request-1")))
+	handler(writerMock, r)
+
+	writerMock.AssertExpectations(t)
+	entries := logs.FilterMessage("Unable to write response").All()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "request-1", entries[0].ContextMap()["request_id"])
+		assert.Equal(t, "could not write", entries[0].ContextMap()["error"])
+	}
+}
+
+func TestNew_Span(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       response.Response
+		writeBodyError error
+		wantStatus     codes.Code
+		wantEvents     []string
+	}{
+		{
+			name:       "Successful response",
+			response:   response.New(http.StatusOK, []byte("test body")),
+			wantStatus: codes.Unset,
+		},
+		{
+			name:       "Client error response is recorded",
+			response:   response.NewError(http.StatusBadRequest).JsonResponse(),
+			wantStatus: codes.Unset,
+			wantEvents: []string{"error_response"},
+		},
+		{
+			name:       "Server error response is recorded",
+			response:   response.NewError(http.StatusInternalServerError).JsonResponse(),
+			wantStatus: codes.Error,
+			wantEvents: []string{"error_response"},
+		},
+		{
+			name:           "Write failure is recorded",
+			response:       response.New(http.StatusOK, []byte("test body")),
+			writeBodyError: errors.New("could not writer to writer error"),
+			wantStatus:     codes.Unset,
+			wantEvents:     []string{"exception"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w http.ResponseWriter = httptest.NewRecorder()
+			if tt.writeBodyError != nil {
+				writerMock := new(mock.ResponseWriter)
+				writerMock.On("Header").Return(http.Header{})
+				writerMock.On("WriteHeader", tt.response.StatusCode).Once()
+				writerMock.On("Write", tt.response.BodyEncoded).Return(0, tt.writeBodyError).Once()
+				w = writerMock
+			}
+
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			ctx, span := provider.Tracer("test").Start(context.Background(), "test")
+
+			handler := New(func(*http.Request) response.Response {
+				return tt.response
+			})
+			handler(w, (&http.Request{}).WithContext(ctx))
+			span.End()
+
+			spans := recorder.Ended()
+			if !assert.Len(t, spans, 1) {
+				return
+			}
+			assert.Equal(t, tt.wantStatus, spans[0].Status().Code)
+			var events []string
+			for _, e := range spans[0].Events() {
+				events = append(events, e.Name)
+			}
+			assert.Equal(t, tt.wantEvents, events)
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var errPatchInvalid = errors.New("invalid patch")
+var errPatchConflict = errors.New("patch conflicts with the current state")
+
+// jsonPatchOperation is an operation of a JSON Patch document (RFC 6902).
+type jsonPatchOperation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// ApplyMergePatch applies a JSON Merge Patch (RFC 7396) to a JSON document, returning the patched document.
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodeJsonValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodeJsonValue(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errPatchInvalid, err)
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+// ApplyJsonPatch applies a JSON Patch (RFC 6902) to a JSON document, returning the patched document. The operations
+// are applied in order, and the document is not patched if any operation fails.
+func ApplyJsonPatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodeJsonValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	var ops []jsonPatchOperation
+	dec := json.NewDecoder(bytes.NewReader(patch))
+	dec.UseNumber()
+	if err := dec.Decode(&ops); err != nil {
+		return nil, fmt.Errorf("%w: %w", errPatchInvalid, err)
+	}
+
+	for i, op := range ops {
+		if target, err = applyJsonPatchOperation(target, op); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func applyJsonPatchOperation(doc any, op jsonPatchOperation) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", errPatchInvalid)
+	}
+	path, err := parseJsonPointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", errPatchInvalid)
+		}
+		if value, err = decodeJsonValue(*op.Value); err != nil {
+			return nil, fmt.Errorf("%w: %w", errPatchInvalid, err)
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: missing from", errPatchInvalid)
+		}
+		from, err := parseJsonPointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" && len(from) < len(path) && isJsonPointerPrefix(from, path) {
+			return nil, fmt.Errorf("%w: cannot move a value into one of its children", errPatchInvalid)
+		}
+		if value, err = getJsonPointer(doc, from); err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if doc, err = removeJsonPointer(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			// Copy the value, so later operations do not change both
+			if value, err = copyJsonValue(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return addJsonPointer(doc, path, value)
+	case "remove":
+		return removeJsonPointer(doc, path)
+	case "replace":
+		if _, err := getJsonPointer(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		if doc, err = removeJsonPointer(doc, path); err != nil {
+			return nil, err
+		}
+		return addJsonPointer(doc, path, value)
+	case "test":
+		current, err := getJsonPointer(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(current, value) {
+			return nil, fmt.Errorf("%w: test failed at %s", errPatchConflict, *op.Path)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", errPatchInvalid, op.Op)
+}
+
+// parseJsonPointer parses a JSON Pointer (RFC 6901) into its reference tokens.
+func parseJsonPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: invalid path %q", errPatchInvalid, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isJsonPointerPrefix(prefix, path []string) bool {
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func getJsonPointer(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: path not found", errPatchConflict)
+			}
+			doc = v
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: path not found", errPatchConflict)
+		}
+	}
+	return doc, nil
+}
+
+func addJsonPointer(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return updateJsonPointer(doc, path, func(container any, token string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			if token == "-" {
+				return append(node, value), nil
+			}
+			i, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("%w: path not found", errPatchConflict)
+	})
+}
+
+func removeJsonPointer(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", errPatchInvalid)
+	}
+	return updateJsonPointer(doc, path, func(container any, token string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("%w: path not found", errPatchConflict)
+			}
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: path not found", errPatchConflict)
+	})
+}
+
+// updateJsonPointer calls fn with the container of the last token of the path, replacing the container with the
+// returned value.
+func updateJsonPointer(doc any, path []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: path not found", errPatchConflict)
+		}
+		updated, err := updateJsonPointer(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[path[0]] = updated
+		return node, nil
+	case []any:
+		i, err := arrayIndex(path[0], len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := updateJsonPointer(node[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = updated
+		return node, nil
+	}
+	return nil, fmt.Errorf("%w: path not found", errPatchConflict)
+}
+
+// arrayIndex parses an array index token, which must be between 0 and max.
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: invalid array index %q", errPatchInvalid, token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > max {
+		return 0, fmt.Errorf("%w: array index %s out of bounds", errPatchConflict, token)
+	}
+	return i, nil
+}
+
+func decodeJsonValue(b []byte) (any, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func copyJsonValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJsonValue(b)
+}
+
+// jsonEqual reports whether two decoded JSON values are equal, comparing numbers by value.
+func jsonEqual(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aErr := a.Float64()
+		bf, bErr := b.Float64()
+		return aErr == nil && bErr == nil && af == bf
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if bv, exists := b[k]; !exists || !jsonEqual(v, bv) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-http/query"
+	"github.com/ellogroup/ello-golang-http/response"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultMaxFieldSize is the maximum size of a text field in a multipart form, when not set in the limits.
+const defaultMaxFieldSize = 1 << 20
+
+var errFileTooLarge = errors.New("file too large")
+
+var validate = query.NewValidator()
+
+// MultipartLimits sets the limits enforced when parsing a multipart form. Zero values are unlimited, except for
+// MaxFieldSize which defaults to 1MB.
+type MultipartLimits struct {
+	// MaxFileSize is the maximum size of each file, in bytes.
+	MaxFileSize int64
+	// MaxTotalSize is the maximum size of the request body, in bytes.
+	MaxTotalSize int64
+	// MaxFieldSize is the maximum size of each text field, in bytes.
+	MaxFieldSize int64
+	// MaxFiles is the maximum number of files.
+	MaxFiles int
+	// AllowedTypes are the allowed media types of files, detected by sniffing the content rather than trusting the
+	// declared Content-Type. Wildcard subtypes are supported, e.g. "image/*".
+	AllowedTypes []string
+}
+
+// FilePart is a file within a multipart form. The content is streamed from the request body, so must be read (or
+// copied elsewhere) before the callback returns.
+type FilePart struct {
+	io.Reader
+	// FieldName is the name of the form field.
+	FieldName string
+	// FileName is the file name provided by the client, which should not be trusted.
+	FileName string
+	// DeclaredType is the Content-Type declared by the client, which should not be trusted.
+	DeclaredType string
+	// DetectedType is the media type detected by sniffing the content.
+	DetectedType string
+}
+
+// ParseMultipart parses a multipart/form-data request body, streaming each file to onFile without buffering whole
+// files in memory. Once all parts have been read, text fields are decoded into fields (if not nil) using `form` struct
+// tags and validated using `validate` struct tags.
+//
+// The returned error will be response.ErrorDetails when the request is invalid: 415 Unsupported Media Type if the
+// request is not a multipart form or a file is not an allowed type, 413 Request Entity Too Large if a size limit is
+// exceeded, or 400 Bad Request if the form is malformed, has too many files or fails validation. The meta of the error
+// holds the details of each invalid field. Errors returned by onFile are returned unchanged.
+func ParseMultipart(r *http.Request, limits MultipartLimits, fields any, onFile func(FilePart) error) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return response.NewError(http.StatusUnsupportedMediaType)
+	}
+	if limits.MaxTotalSize > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, limits.MaxTotalSize)
+	}
+	if limits.MaxFieldSize == 0 {
+		limits.MaxFieldSize = defaultMaxFieldSize
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return response.NewError(http.StatusBadRequest).WithMessage("Malformed multipart form")
+	}
+
+	values := url.Values{}
+	files := 0
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return multipartReadError(err, limits)
+		}
+
+		name := part.FormName()
+		if name == "" {
+			_ = part.Close()
+			continue
+		}
+
+		if part.FileName() == "" {
+			value, err := readField(part, limits.MaxFieldSize)
+			if err != nil {
+				if errors.Is(err, errFileTooLarge) {
+					return tooLarge(name, fmt.Sprintf("exceeds maximum size of %d bytes", limits.MaxFieldSize))
+				}
+				return multipartReadError(err, limits)
+			}
+			values.Add(name, value)
+			continue
+		}
+
+		files++
+		if limits.MaxFiles > 0 && files > limits.MaxFiles {
+			return response.NewError(http.StatusBadRequest).
+				WithMessage(fmt.Sprintf("Too many files, maximum is %d", limits.MaxFiles)).
+				WithMeta(map[string]string{name: "too many files"})
+		}
+		if err := handleFile(part, limits, onFile); err != nil {
+			return err
+		}
+	}
+
+	if fields == nil {
+		return nil
+	}
+	if errs := validate.BindTag(values, fields, "form"); len(errs) > 0 {
+		return query.ErrorDetails(errs)
+	}
+	return nil
+}
+
+// handleFile sniffs the content type of a file part, checks it is allowed, and passes it to onFile. Any content not
+// read by onFile is drained, so that the size limits are enforced.
+func handleFile(part *multipart.Part, limits MultipartLimits, onFile func(FilePart) error) error {
+	name := part.FormName()
+	content := io.Reader(part)
+	if limits.MaxFileSize > 0 {
+		content = &limitedReader{r: part, remaining: limits.MaxFileSize}
+	}
+
+	buffered := bufio.NewReaderSize(content, 512)
+	sniff, err := buffered.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return fileReadError(name, err, limits)
+	}
+	detected := http.DetectContentType(sniff)
+	if !allowedType(detected, limits.AllowedTypes) {
+		return response.NewError(http.StatusUnsupportedMediaType).
+			WithMeta(map[string]string{name: fmt.Sprintf("file type %s is not allowed", strings.Split(detected, ";")[0])})
+	}
+
+	err = onFile(FilePart{
+		Reader:       buffered,
+		FieldName:    name,
+		FileName:     part.FileName(),
+		DeclaredType: part.Header.Get("Content-Type"),
+		DetectedType: detected,
+	})
+	if err == nil {
+		_, err = io.Copy(io.Discard, buffered)
+	}
+	if err != nil {
+		return fileReadError(name, err, limits)
+	}
+	return nil
+}
+
+func readField(part *multipart.Part, maxSize int64) (string, error) {
+	b, err := io.ReadAll(&limitedReader{r: part, remaining: maxSize})
+	return string(b), err
+}
+
+// allowedType reports whether the detected content type matches one of the allowed media types. All types are
+// allowed if none are set.
+func allowedType(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, a := range allowed {
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(mediaType, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func fileReadError(name string, err error, limits MultipartLimits) error {
+	if errors.Is(err, errFileTooLarge) {
+		return tooLarge(name, fmt.Sprintf("exceeds maximum size of %d bytes", limits.MaxFileSize))
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return multipartReadError(err, limits)
+	}
+	var details response.ErrorDetails
+	if errors.As(err, &details) {
+		return err
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, multipart.ErrMessageTooLarge) {
+		return response.NewError(http.StatusBadRequest).WithMessage("Malformed multipart form")
+	}
+	return err
+}
+
+func multipartReadError(err error, limits MultipartLimits) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return response.NewError(http.StatusRequestEntityTooLarge).
+			WithMessage(fmt.Sprintf("Request body exceeds maximum size of %d bytes", limits.MaxTotalSize))
+	}
+	return response.NewError(http.StatusBadRequest).WithMessage("Malformed multipart form")
+}
+
+func tooLarge(name, message string) error {
+	return response.NewError(http.StatusRequestEntityTooLarge).WithMeta(map[string]string{name: message})
+}
+
+// limitedReader reads from r, returning errFileTooLarge if more than remaining bytes are available.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, errFileTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), errFileTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A" + strings.Repeat("\x00", 32))
+
+type uploadFields struct {
+	Title string `form:"title" validate:"required"`
+	Count int    `form:"count" validate:"omitempty,max=10"`
+}
+
+type multipartFile struct {
+	field, name, contentType string
+	content                  []byte
+}
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files []multipartFile) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		assert.NoError(t, mw.WriteField(k, v))
+	}
+	for _, f := range files {
+		h := make(map[string][]string)
+		h["Content-Disposition"] = []string{`form-data; name="` + f.field + `"; filename="` + f.name + `"`}
+		h["Content-Type"] = []string{f.contentType}
+		w, err := mw.CreatePart(h)
+		assert.NoError(t, err)
+		_, err = w.Write(f.content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestParseMultipart(t *testing.T) {
+	tests := []struct {
+		name          string
+		request       func(t *testing.T) *http.Request
+		limits        MultipartLimits
+		onFileErr     error
+		wantFields    uploadFields
+		wantFiles     []FilePart
+		wantContents  []string
+		wantStatus    int
+		wantMeta      map[string]string
+		wantErrIsFile bool
+	}{
+		{
+			name: "Fields and files are parsed",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, map[string]string{"title": "Holiday", "count": "2"}, []multipartFile{
+					{field: "photo", name: "beach.png", contentType: "image/jpeg", content: pngHeader},
+					{field: "notes", name: "notes.txt", contentType: "text/plain", content: []byte("notes")},
+				})
+			},
+			wantFields: uploadFields{Title: "Holiday", Count: 2},
+			wantFiles: []FilePart{
+				{FieldName: "photo", FileName: "beach.png", DeclaredType: "image/jpeg", DetectedType: "image/png"},
+				{FieldName: "notes", FileName: "notes.txt", DeclaredType: "text/plain", DetectedType: "text/plain; charset=utf-8"},
+			},
+			wantContents: []string{string(pngHeader), "notes"},
+		},
+		{
+			name: "Non-multipart request returns unsupported media type",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("{}"))
+				r.Header.Set("Content-Type", "application/json")
+				return r
+			},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "Malformed body returns bad request",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("--abc\r\nbroken"))
+				r.Header.Set("Content-Type", "multipart/form-data; boundary=abc")
+				return r
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Failed validation returns bad request with field details",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, map[string]string{"count": "11"}, nil)
+			},
+			wantFields: uploadFields{},
+			wantStatus: http.StatusBadRequest,
+			wantMeta: map[string]string{
+				"title": "failed on the 'required' rule",
+				"count": "failed on the 'max=10' rule",
+			},
+		},
+		{
+			name: "Disallowed sniffed type returns unsupported media type",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, map[string]string{"title": "test"}, []multipartFile{
+					{field: "photo", name: "photo.png", contentType: "image/png", content: []byte("<html></html>")},
+				})
+			},
+			limits:     MultipartLimits{AllowedTypes: []string{"image/*", "application/pdf"}},
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantMeta:   map[string]string{"photo": "file type text/html is not allowed"},
+		},
+		{
+			name: "Allowed wildcard type is accepted",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, map[string]string{"title": "test"}, []multipartFile{
+					{field: "photo", name: "photo", contentType: "application/octet-stream", content: pngHeader},
+				})
+			},
+			limits:     MultipartLimits{AllowedTypes: []string{"image/*"}},
+			wantFields: uploadFields{Title: "test"},
+			wantFiles: []FilePart{
+				{FieldName: "photo", FileName: "photo", DeclaredType: "application/octet-stream", DetectedType: "image/png"},
+			},
+			wantContents: []string{string(pngHeader)},
+		},
+		{
+			name: "File larger than limit returns too large",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, nil, []multipartFile{
+					{field: "doc", name: "doc.txt", contentType: "text/plain", content: []byte("0123456789")},
+				})
+			},
+			limits:     MultipartLimits{MaxFileSize: 5},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantMeta:   map[string]string{"doc": "exceeds maximum size of 5 bytes"},
+		},
+		{
+			name: "File at limit is accepted",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, map[string]string{"title": "test"}, []multipartFile{
+					{field: "doc", name: "doc.txt", contentType: "text/plain", content: []byte("01234")},
+				})
+			},
+			limits:     MultipartLimits{MaxFileSize: 5},
+			wantFields: uploadFields{Title: "test"},
+			wantFiles: []FilePart{
+				{FieldName: "doc", FileName: "doc.txt", DeclaredType: "text/plain", DetectedType: "text/plain; charset=utf-8"},
+			},
+			wantContents: []string{"01234"},
+		},
+		{
+			name: "Body larger than total limit returns too large",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, nil, []multipartFile{
+					{field: "doc", name: "doc.txt", contentType: "text/plain", content: bytes.Repeat([]byte("a"), 2048)},
+				})
+			},
+			limits: MultipartLimits{MaxTotalSize: 1024},
+			wantFiles: []FilePart{
+				{FieldName: "doc", FileName: "doc.txt", DeclaredType: "text/plain", DetectedType: "text/plain; charset=utf-8"},
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "Field larger than limit returns too large",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, map[string]string{"title": "a long title"}, nil)
+			},
+			limits:     MultipartLimits{MaxFieldSize: 4},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantMeta:   map[string]string{"title": "exceeds maximum size of 4 bytes"},
+		},
+		{
+			name: "Too many files returns bad request",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, nil, []multipartFile{
+					{field: "a", name: "a.txt", contentType: "text/plain", content: []byte("a")},
+					{field: "b", name: "b.txt", contentType: "text/plain", content: []byte("b")},
+				})
+			},
+			limits: MultipartLimits{MaxFiles: 1},
+			wantFiles: []FilePart{
+				{FieldName: "a", FileName: "a.txt", DeclaredType: "text/plain", DetectedType: "text/plain; charset=utf-8"},
+			},
+			wantContents: []string{"a"},
+			wantStatus:   http.StatusBadRequest,
+			wantMeta:     map[string]string{"b": "too many files"},
+		},
+		{
+			name: "Error from callback is returned",
+			request: func(t *testing.T) *http.Request {
+				return newMultipartRequest(t, nil, []multipartFile{
+					{field: "a", name: "a.txt", contentType: "text/plain", content: []byte("a")},
+				})
+			},
+			onFileErr: errors.New("storage error"),
+			wantFiles: []FilePart{
+				{FieldName: "a", FileName: "a.txt", DeclaredType: "text/plain", DetectedType: "text/plain; charset=utf-8"},
+			},
+			wantErrIsFile: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotFields uploadFields
+			var gotFiles []FilePart
+			var gotContents []string
+			err := ParseMultipart(tt.request(t), tt.limits, &gotFields, func(f FilePart) error {
+				gotFiles = append(gotFiles, FilePart{
+					FieldName:    f.FieldName,
+					FileName:     f.FileName,
+					DeclaredType: f.DeclaredType,
+					DetectedType: f.DetectedType,
+				})
+				if tt.onFileErr != nil {
+					return tt.onFileErr
+				}
+				b, err := io.ReadAll(f)
+				if err != nil {
+					return err
+				}
+				gotContents = append(gotContents, string(b))
+				return nil
+			})
+
+			switch {
+			case tt.wantErrIsFile:
+				assert.ErrorIs(t, err, tt.onFileErr)
+			case tt.wantStatus == 0:
+				assert.NoError(t, err)
+				assert.Equalf(t, tt.wantFields, gotFields, "fields")
+			default:
+				details := response.AsErrorDetails(err)
+				assert.Equalf(t, tt.wantStatus, details.Status, "status (%v)", err)
+				if tt.wantMeta != nil {
+					assert.Equalf(t, tt.wantMeta, details.Meta, "meta")
+				}
+			}
+			assert.Equalf(t, tt.wantFiles, gotFiles, "files")
+			assert.Equalf(t, tt.wantContents, gotContents, "contents")
+		})
+	}
+}
+
+func Test_allowedType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		allowed     []string
+		want        bool
+	}{
+		{"image/png", nil, true},
+		{"image/png", []string{"image/png"}, true},
+		{"image/png", []string{"image/*"}, true},
+		{"text/plain; charset=utf-8", []string{"text/plain"}, true},
+		{"text/html; charset=utf-8", []string{"text/plain", "image/*"}, false},
+		{"application/pdf", []string{"application/json"}, false},
+	}
+	for _, tt := range tests {
+		got := allowedType(tt.contentType, tt.allowed)
+		assert.Equalf(t, tt.want, got, "allowedType(%v, %v)", tt.contentType, tt.allowed)
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-http/query"
+	"github.com/ellogroup/ello-golang-http/response"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const ContentTypeMergePatch = "application/merge-patch+json"
+const ContentTypeJsonPatch = "application/json-patch+json"
+
+// Patch applies the patch in the request body to resource, which must be a pointer to a struct holding the current
+// value of the resource. The patch is a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), selected by the
+// Content-Type of the request. The patched value is validated using `validate` struct tags, and resource is only
+// updated if the patch is applied successfully. Fields with a `json:"-"` tag are not changed by the patch.
+//
+// The returned error will be response.ErrorDetails when the request is invalid: 415 Unsupported Media Type for any
+// other Content-Type, 400 Bad Request if the patch is malformed, 409 Conflict if a JSON Patch can not be applied to the
+// current value (e.g. a test operation fails or a path does not exist), or 422 Unprocessable Entity if the patched
+// value is not valid. The meta of a 422 error holds the details of each invalid field.
+func Patch(r *http.Request, resource any) error {
+	rv := reflect.ValueOf(resource)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("patch resource must be a pointer to a struct, got %T", resource)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch mediaType {
+	case ContentTypeMergePatch:
+		apply = ApplyMergePatch
+	case ContentTypeJsonPatch:
+		apply = ApplyJsonPatch
+	default:
+		return response.NewError(http.StatusUnsupportedMediaType).
+			WithMessage(fmt.Sprintf("Content-Type must be %s or %s", ContentTypeMergePatch, ContentTypeJsonPatch))
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		return jsonDecodeError(err)
+	}
+	doc, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	patched, err := apply(doc, patch)
+	if err != nil {
+		return patchError(err)
+	}
+
+	// Decode into a copy with the JSON fields cleared, so fields removed by the patch are zeroed, and fields hidden
+	// from JSON are kept
+	result := reflect.New(rv.Elem().Type())
+	result.Elem().Set(rv.Elem())
+	walkFields(result.Elem(), func(field reflect.StructField, v reflect.Value) {
+		if field.Tag.Get("json") != "-" && v.CanSet() {
+			v.SetZero()
+		}
+	})
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(result.Interface()); err != nil {
+		return patchResultError(patched, err)
+	}
+
+	if errs := validate.ValidateStruct(result.Interface(), "json"); len(errs) > 0 {
+		if err, ok := errs[""]; ok {
+			return err
+		}
+		return query.ErrorDetailsWithStatus(http.StatusUnprocessableEntity, errs)
+	}
+
+	rv.Elem().Set(result.Elem())
+	return nil
+}
+
+func patchError(err error) error {
+	switch {
+	case errors.Is(err, errPatchConflict):
+		return response.NewError(http.StatusConflict).WithMessage(err.Error())
+	case errors.Is(err, errPatchInvalid):
+		return response.NewError(http.StatusBadRequest).WithMessage(err.Error())
+	}
+	return err
+}
+
+func patchResultError(patched []byte, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if path := jsonPathAt(patched, typeErr.Offset); path != "" {
+			return response.NewError(http.StatusUnprocessableEntity).
+				WithMeta(map[string]string{path: fmt.Sprintf("must be of type %s", typeErr.Type)})
+		}
+	}
+	if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return response.NewError(http.StatusUnprocessableEntity).
+			WithMeta(map[string]string{field: "unknown field"})
+	}
+	return response.NewError(http.StatusUnprocessableEntity).WithMessage("Patched resource is not valid")
+}
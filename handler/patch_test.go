@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type patchAddress struct {
+	City     string `json:"city" validate:"required"`
+	Postcode string `json:"postcode,omitempty"`
+}
+
+type patchResource struct {
+	Id       int               `json:"id"`
+	Name     string            `json:"name" validate:"required,max=20"`
+	Nickname *string           `json:"nickname"`
+	Address  patchAddress      `json:"address"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Version  int               `json:"-"`
+}
+
+func newPatchResource() patchResource {
+	nickname := "tess"
+	return patchResource{
+		Id:       1,
+		Name:     "Test",
+		Nickname: &nickname,
+		Address:  patchAddress{City: "London", Postcode: "N1"},
+		Tags:     []string{"a", "b"},
+		Labels:   map[string]string{"team": "red", "tier": "gold"},
+		Version:  3,
+	}
+}
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        func(r *patchResource)
+		wantStatus  int
+		wantMeta    map[string]string
+	}{
+		{
+			name:        "Merge patch updates, adds and removes fields",
+			contentType: ContentTypeMergePatch,
+			body:        `{"name":"Updated","nickname":null,"address":{"postcode":null},"labels":{"tier":null}}`,
+			want: func(r *patchResource) {
+				r.Name = "Updated"
+				r.Nickname = nil
+				r.Address.Postcode = ""
+				r.Labels = map[string]string{"team": "red"}
+			},
+		},
+		{
+			name:        "JSON patch applies operations",
+			contentType: ContentTypeJsonPatch + "; charset=utf-8",
+			body: `[{"op":"test","path":"/name","value":"Test"},{"op":"add","path":"/tags/-","value":"c"},` +
+				`{"op":"remove","path":"/tags/0"},{"op":"replace","path":"/address/city","value":"Leeds"}]`,
+			want: func(r *patchResource) {
+				r.Tags = []string{"b", "c"}
+				r.Address.City = "Leeds"
+			},
+		},
+		{
+			name:        "Unsupported content type",
+			contentType: "application/json",
+			body:        `{"name":"Updated"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "Malformed merge patch",
+			contentType: ContentTypeMergePatch,
+			body:        `{"name":`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Malformed JSON patch",
+			contentType: ContentTypeJsonPatch,
+			body:        `[{"op":"add"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "JSON patch test fails",
+			contentType: ContentTypeJsonPatch,
+			body:        `[{"op":"test","path":"/name","value":"Other"},{"op":"replace","path":"/name","value":"Updated"}]`,
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "Patched value fails validation",
+			contentType: ContentTypeMergePatch,
+			body:        `{"name":null,"address":{"city":null}}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantMeta: map[string]string{
+				"name":         "failed on the 'required' rule",
+				"Address.City": "failed on the 'required' rule",
+			},
+		},
+		{
+			name:        "Patched value has wrong type",
+			contentType: ContentTypeMergePatch,
+			body:        `{"tags":[1]}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantMeta:    map[string]string{"tags[0]": "must be of type string"},
+		},
+		{
+			name:        "Patched value has unknown field",
+			contentType: ContentTypeJsonPatch,
+			body:        `[{"op":"add","path":"/version","value":4}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantMeta:    map[string]string{"version": "unknown field"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			got := newPatchResource()
+			err := Patch(r, &got)
+			if tt.wantStatus == 0 {
+				assert.NoError(t, err)
+				want := newPatchResource()
+				tt.want(&want)
+				assert.Equal(t, want, got)
+				return
+			}
+
+			assert.Equal(t, newPatchResource(), got, "resource is unchanged")
+			details := response.AsErrorDetails(err)
+			assert.Equalf(t, tt.wantStatus, details.Status, "status (%v)", err)
+			if tt.wantMeta != nil {
+				assert.Equalf(t, tt.wantMeta, details.Meta, "meta")
+			}
+		})
+	}
+}
+
+func TestPatch_InvalidResource(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", ContentTypeMergePatch)
+	assert.Error(t, Patch(r, newPatchResource()))
+}
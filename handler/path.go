@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"github.com/ellogroup/ello-golang-http/query"
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// PathString returns the value of a chi path parameter, validated against the validation rules (if not empty).
+//
+// The returned error will be response.ErrorDetails: 404 Not Found if the parameter is missing or empty, or 400 Bad
+// Request if it fails validation. The meta of a 400 error holds the details of the invalid parameter.
+func PathString(r *http.Request, name, rules string) (string, error) {
+	return pathParam[string](r, name, rules)
+}
+
+// PathInt returns the value of a chi path parameter as an integer, validated against the validation rules (if not
+// empty). Rules such as "min=1" apply to the integer value.
+//
+// The returned error will be response.ErrorDetails: 404 Not Found if the parameter is missing or empty, or 400 Bad
+// Request if it is not an integer or fails validation.
+func PathInt(r *http.Request, name, rules string) (int, error) {
+	return pathParam[int](r, name, rules)
+}
+
+// PathUUID returns the value of a chi path parameter as a UUID, validated against the validation rules (if not empty).
+//
+// The returned error will be response.ErrorDetails: 404 Not Found if the parameter is missing or empty, or 400 Bad
+// Request if it is not a UUID or fails validation.
+func PathUUID(r *http.Request, name, rules string) (uuid.UUID, error) {
+	return pathParam[uuid.UUID](r, name, rules)
+}
+
+// BindPath decodes the chi path parameters into dst, which must be a pointer to a struct, using `path` struct tags,
+// and then validates dst using `validate` struct tags. Field types are decoded the same way as query.Decode.
+//
+// The returned error will be response.ErrorDetails when the request is invalid: 404 Not Found if a tagged parameter
+// is missing or empty, or 400 Bad Request if a parameter can not be decoded or fails validation. The meta of a 400
+// error holds the details of each invalid parameter.
+func BindPath(r *http.Request, dst any) error {
+	values := pathValues(r)
+	errs := validate.BindTag(values, dst, "path")
+	if err, ok := errs[""]; ok {
+		return err
+	}
+	for _, name := range tagNames(reflect.TypeOf(dst).Elem(), "path") {
+		if values.Get(name) == "" {
+			return response.NewError(http.StatusNotFound)
+		}
+	}
+	if len(errs) > 0 {
+		return query.ErrorDetails(errs)
+	}
+	return nil
+}
+
+func pathParam[T any](r *http.Request, name, rules string) (T, error) {
+	var v T
+	s := chi.URLParam(r, name)
+	if s == "" {
+		return v, response.NewError(http.StatusNotFound)
+	}
+	if err := query.DecodeValue(reflect.ValueOf(&v).Elem(), []string{s}); err != nil {
+		return v, query.ErrorDetails(map[string]error{name: err})
+	}
+	if rules != "" {
+		if err := validate.ValidateVar(v, rules); err != nil {
+			return v, query.ErrorDetails(map[string]error{name: err})
+		}
+	}
+	return v, nil
+}
+
+// pathValues returns the chi path parameters of the request. Later parameters of the same name (from sub-routers)
+// take precedence, matching chi.URLParam.
+func pathValues(r *http.Request) url.Values {
+	values := url.Values{}
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return values
+	}
+	for i, key := range rctx.URLParams.Keys {
+		values.Set(key, rctx.URLParams.Values[i])
+	}
+	return values
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"context"
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newPathRequest returns a request with the chi path parameters set, as if it had been routed by chi.
+func newPathRequest(params map[string]string) *http.Request {
+	return withPathParams(httptest.NewRequest(http.MethodGet, "/", nil), params)
+}
+
+// withPathParams returns a copy of the request with the chi path parameters set, as if it had been routed by chi.
+func withPathParams(r *http.Request, params map[string]string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestPathInt(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     map[string]string
+		rules      string
+		want       int
+		wantStatus int
+		wantMeta   map[string]string
+	}{
+		{
+			name:   "Valid integer",
+			params: map[string]string{"id": "42"},
+			rules:  "min=1",
+			want:   42,
+		},
+		{
+			name:   "No rules",
+			params: map[string]string{"id": "-1"},
+			want:   -1,
+		},
+		{
+			name:       "Missing parameter",
+			params:     map[string]string{},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Not an integer",
+			params:     map[string]string{"id": "abc"},
+			wantStatus: http.StatusBadRequest,
+			wantMeta:   map[string]string{"id": `invalid integer "abc"`},
+		},
+		{
+			name:       "Fails validation",
+			params:     map[string]string{"id": "0"},
+			rules:      "min=1",
+			wantStatus: http.StatusBadRequest,
+			wantMeta:   map[string]string{"id": "failed on the 'min=1' rule"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PathInt(newPathRequest(tt.params), "id", tt.rules)
+			if tt.wantStatus == 0 {
+				assert.NoError(t, err)
+				assert.Equalf(t, tt.want, got, "PathInt()")
+				return
+			}
+			details := response.AsErrorDetails(err)
+			assert.Equalf(t, tt.wantStatus, details.Status, "status (%v)", err)
+			if tt.wantMeta != nil {
+				assert.Equalf(t, tt.wantMeta, details.Meta, "meta")
+			}
+		})
+	}
+}
+
+func TestPathUUID(t *testing.T) {
+	id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	got, err := PathUUID(newPathRequest(map[string]string{"id": id.String()}), "id", "")
+	assert.NoError(t, err)
+	assert.Equal(t, id, got)
+
+	_, err = PathUUID(newPathRequest(map[string]string{"id": "not-a-uuid"}), "id", "")
+	assert.Equal(t, http.StatusBadRequest, response.AsErrorDetails(err).Status)
+
+	_, err = PathUUID(httptest.NewRequest(http.MethodGet, "/", nil), "id", "")
+	assert.Equal(t, http.StatusNotFound, response.AsErrorDetails(err).Status, "request not routed by chi")
+}
+
+func TestPathString(t *testing.T) {
+	got, err := PathString(newPathRequest(map[string]string{"slug": "hello-world"}), "slug", "max=20")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello-world", got)
+
+	_, err = PathString(newPathRequest(map[string]string{"slug": "hello world"}), "slug", "alpha")
+	details := response.AsErrorDetails(err)
+	assert.Equal(t, http.StatusBadRequest, details.Status)
+	assert.Equal(t, map[string]string{"slug": "failed on the 'alpha' rule"}, details.Meta)
+}
+
+type pathTarget struct {
+	OrgId  uuid.UUID `path:"orgId"`
+	UserId int       `path:"userId" validate:"min=1"`
+}
+
+func TestBindPath(t *testing.T) {
+	orgId := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	tests := []struct {
+		name       string
+		params     map[string]string
+		want       pathTarget
+		wantStatus int
+		wantMeta   map[string]string
+	}{
+		{
+			name:   "Valid parameters are decoded",
+			params: map[string]string{"orgId": orgId.String(), "userId": "7"},
+			want:   pathTarget{OrgId: orgId, UserId: 7},
+		},
+		{
+			name:       "Missing parameter",
+			params:     map[string]string{"orgId": orgId.String()},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Invalid parameters",
+			params:     map[string]string{"orgId": "abc", "userId": "0"},
+			wantStatus: http.StatusBadRequest,
+			wantMeta: map[string]string{
+				"orgId":  `invalid value "abc": invalid UUID length: 3`,
+				"userId": "failed on the 'min=1' rule",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got pathTarget
+			err := BindPath(newPathRequest(tt.params), &got)
+			if tt.wantStatus == 0 {
+				assert.NoError(t, err)
+				assert.Equalf(t, tt.want, got, "BindPath()")
+				return
+			}
+			details := response.AsErrorDetails(err)
+			assert.Equalf(t, tt.wantStatus, details.Status, "status (%v)", err)
+			if tt.wantMeta != nil {
+				assert.Equalf(t, tt.wantMeta, details.Meta, "meta")
+			}
+		})
+	}
+}
+
+func TestBindPath_Router(t *testing.T) {
+	var got pathTarget
+	router := chi.NewRouter()
+	router.Get("/orgs/{orgId}/users/{userId}", New(func(r *http.Request) response.Response {
+		if err := BindPath(r, &got); err != nil {
+			return response.AsErrorDetails(err).JsonResponse()
+		}
+		return response.NewNoContent(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orgs/6ba7b810-9dad-11d1-80b4-00c04fd430c8/users/3", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, pathTarget{OrgId: uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), UserId: 3}, got)
+}
@@ -0,0 +1,52 @@
+package recorder
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// ResponseRecorder is an implementation of http.ResponseWriter that records the response in memory, so it can be
+// inspected or replayed to another response writer.
+type ResponseRecorder struct {
+	StatusCode  int
+	HeaderMap   http.Header
+	Body        bytes.Buffer
+	wroteHeader bool
+}
+
+func New() *ResponseRecorder {
+	return &ResponseRecorder{
+		StatusCode: http.StatusOK,
+		HeaderMap:  http.Header{},
+	}
+}
+
+func (r *ResponseRecorder) Header() http.Header {
+	return r.HeaderMap
+}
+
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.Body.Write(b)
+}
+
+func (r *ResponseRecorder) WriteHeader(statusCode int) {
+	if r.wroteHeader {
+		return
+	}
+	r.StatusCode = statusCode
+	r.wroteHeader = true
+}
+
+// WriteTo replays the recorded response to the response writer.
+func (r *ResponseRecorder) WriteTo(w http.ResponseWriter) error {
+	for k, v := range r.HeaderMap {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(r.StatusCode)
+	if r.Body.Len() == 0 {
+		return nil
+	}
+	_, err := w.Write(r.Body.Bytes())
+	return err
+}
@@ -0,0 +1,62 @@
+package recorder
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    func(w http.ResponseWriter)
+		wantStatus int
+		wantHeader http.Header
+		wantBody   string
+	}{
+		{
+			name:       "Nothing written defaults to status OK",
+			handler:    func(w http.ResponseWriter) {},
+			wantStatus: http.StatusOK,
+			wantHeader: http.Header{},
+		},
+		{
+			name: "Status code, headers and body are recorded",
+			handler: func(w http.ResponseWriter) {
+				w.Header().Set("X-Test", "test")
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte("test body"))
+			},
+			wantStatus: http.StatusCreated,
+			wantHeader: http.Header{"X-Test": {"test"}},
+			wantBody:   "test body",
+		},
+		{
+			name: "Only first status code is recorded",
+			handler: func(w http.ResponseWriter) {
+				_, _ = w.Write([]byte("test body"))
+				w.WriteHeader(http.StatusCreated)
+			},
+			wantStatus: http.StatusOK,
+			wantHeader: http.Header{},
+			wantBody:   "test body",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := New()
+			tt.handler(rec)
+
+			assert.Equalf(t, tt.wantStatus, rec.StatusCode, "StatusCode")
+			assert.Equalf(t, tt.wantHeader, rec.HeaderMap, "HeaderMap")
+			assert.Equalf(t, tt.wantBody, rec.Body.String(), "Body")
+
+			w := httptest.NewRecorder()
+			assert.NoError(t, rec.WriteTo(w))
+			assert.Equalf(t, tt.wantStatus, w.Code, "WriteTo() status code")
+			assert.Equalf(t, tt.wantHeader, w.Header(), "WriteTo() header")
+			assert.Equalf(t, tt.wantBody, w.Body.String(), "WriteTo() body")
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/ellogroup/ello-golang-http/response"
+	"go.uber.org/zap"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// NewAssertContentTypeMiddleware returns a handler to be used as middleware. This middleware will assert the request
+// content type is one of the given media types, ignoring any parameters such as charset, otherwise it will return an
+// error and prevent the request from being processed. The allowed media types are returned in the Accept-Patch header
+// of the error for PATCH requests (RFC 5789), and in the Accept header otherwise.
+func NewAssertContentTypeMiddleware(mediaTypes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if !slices.ContainsFunc(mediaTypes, func(t string) bool { return strings.EqualFold(t, mediaType) }) {
+				Log(r.Context(), zap.DebugLevel, "Unexpected Content-Type provided",
+					zap.String("Content-Type", r.Header.Get("Content-Type")))
+				acceptHeader := "Accept"
+				if r.Method == http.MethodPatch {
+					acceptHeader = "Accept-Patch"
+				}
+				resp := response.NewError(http.StatusUnsupportedMediaType).JsonResponse().
+					WithHeader(acceptHeader, strings.Join(mediaTypes, ", "))
+				if err := resp.WriteTo(w); err != nil {
+					// Unable to write the response to the response writer
+					Log(r.Context(), zap.ErrorLevel, "Unable to write response", zap.Error(err))
+				}
+				return
+			}
+
+			// Call the next handler in the chain
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewAssertContentTypeMiddleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		contentType     string
+		wantNextCalled  bool
+		wantAccept      string
+		wantAcceptPatch string
+	}{
+		{
+			name:           "Allowed content type calls next in chain",
+			method:         http.MethodPatch,
+			contentType:    "application/merge-patch+json",
+			wantNextCalled: true,
+		},
+		{
+			name:           "Parameters and case are ignored",
+			method:         http.MethodPatch,
+			contentType:    "Application/JSON-Patch+JSON; charset=utf-8",
+			wantNextCalled: true,
+		},
+		{
+			name:            "Other content type on PATCH sets Accept-Patch",
+			method:          http.MethodPatch,
+			contentType:     "application/json",
+			wantAcceptPatch: "application/merge-patch+json, application/json-patch+json",
+		},
+		{
+			name:       "Missing content type on POST sets Accept",
+			method:     http.MethodPost,
+			wantAccept: "application/merge-patch+json, application/json-patch+json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextCalled := false
+			next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				nextCalled = true
+			})
+
+			r := httptest.NewRequest(tt.method, "/", nil)
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			sut := NewAssertContentTypeMiddleware("application/merge-patch+json", "application/json-patch+json")
+			sut(next).ServeHTTP(rec, r)
+
+			assert.Equalf(t, tt.wantNextCalled, nextCalled, "nextCalled")
+			if !tt.wantNextCalled {
+				assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+			}
+			assert.Equal(t, tt.wantAccept, rec.Header().Get("Accept"))
+			assert.Equal(t, tt.wantAcceptPatch, rec.Header().Get("Accept-Patch"))
+		})
+	}
+}
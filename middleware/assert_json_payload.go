@@ -12,11 +12,11 @@ func NewAssertJsonPayloadMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.Header.Get("Content-Type") != "application/json" {
-				log := Logger(r.Context())
-				log.Debug("Unexpected Content-Type provided", zap.String("Content-Type", r.Header.Get("Content-Type")))
+				Log(r.Context(), zap.DebugLevel, "Unexpected Content-Type provided",
+					zap.String("Content-Type", r.Header.Get("Content-Type")))
 				if err := response.NewError(http.StatusUnsupportedMediaType).JsonResponse().WriteTo(w); err != nil {
 					// Unable to write the response to the response writer
-					log.Error("Unable to write response", zap.Error(err))
+					Log(r.Context(), zap.ErrorLevel, "Unable to write response", zap.Error(err))
 				}
 				return
 			}
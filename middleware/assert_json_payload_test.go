@@ -7,7 +7,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	testifymock "github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -92,3 +94,29 @@ func TestNewAssertJsonPayloadMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestNewAssertJsonPayloadMiddleware_LogCtx(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+
+	writerMock := new(mock.ResponseWriter)
+	writerMock.On("Header").Return(http.Header{})
+	writerMock.On("WriteHeader", http.StatusUnsupportedMediaType).Once()
+	writerMock.On("Write", testifymock.Anything).Return(0, errors.New("could not write")).Once()
+
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	handler := NewLogCtxMiddleware(zap.New(core))(NewAssertJsonPayloadMiddleware()(next))
+	r := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	r.Header.Set("Content-Type", "text/plain")
+	handler.ServeHTTP(writerMock, r)
+
+	writerMock.AssertExpectations(t)
+	// Logged through the logctx middleware logger, with the fields of the request
+	entries := logs.FilterMessage("Unable to write response").All()
+	if !assert.Len(t, entries, 1) {
+		return
+	}
+	assert.Equal(t, zap.ErrorLevel, entries[0].Level)
+	assert.Equal(t, "/orders", entries[0].ContextMap()["path"])
+	assert.Equal(t, "could not write", entries[0].ContextMap()["error"])
+	assert.Equal(t, 1, logs.FilterMessage("Unexpected Content-Type provided").Len())
+}
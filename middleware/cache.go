@@ -0,0 +1,306 @@
+package middleware
+
+import (
+	"context"
+	"github.com/ellogroup/ello-golang-http/internal/recorder"
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheableStatusCodes are the status codes that may be stored by the cache middleware.
+var cacheableStatusCodes = []int{
+	http.StatusOK,
+	http.StatusNonAuthoritativeInfo,
+	http.StatusNoContent,
+	http.StatusMultipleChoices,
+	http.StatusMovedPermanently,
+	http.StatusPermanentRedirect,
+	http.StatusNotFound,
+	http.StatusMethodNotAllowed,
+	http.StatusGone,
+	http.StatusRequestURITooLong,
+	http.StatusNotImplemented,
+}
+
+// CacheOption configures the cache middleware.
+type CacheOption func(*cache)
+
+// WithCacheTTL sets the time a response is considered fresh when it does not include a max-age or s-maxage
+// Cache-Control directive. By default, only responses with an explicit max-age or s-maxage are stored.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithCacheStaleWhileRevalidate sets the time a stale response can be served while it is revalidated in the background,
+// when the response does not include a stale-while-revalidate Cache-Control directive.
+func WithCacheStaleWhileRevalidate(d time.Duration) CacheOption {
+	return func(c *cache) {
+		c.staleWhileRevalidate = d
+	}
+}
+
+// WithCacheVary sets the request headers that are included in the cache key. Responses that vary on any other header
+// will not be stored.
+func WithCacheVary(headers ...string) CacheOption {
+	return func(c *cache) {
+		for _, h := range headers {
+			c.vary = append(c.vary, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+type cache struct {
+	store                CacheStore
+	ttl                  time.Duration
+	staleWhileRevalidate time.Duration
+	vary                 []string
+	group                cacheFlightGroup
+	now                  func() time.Time
+	// revalidating holds the keys of the stale entries being revalidated in the background
+	revalidating sync.Map
+}
+
+// NewCacheMiddleware returns a handler to be used as middleware. This middleware will store complete responses to GET
+// and HEAD requests, and serve them to subsequent requests with the same method, path, query and vary headers while
+// they are fresh. Cache-Control directives on both the request and the response are respected. Concurrent requests
+// for the same uncached response are coalesced, so the next handler is only called once.
+//
+// Stale responses can be served while they are revalidated in the background, if allowed by the stale-while-revalidate
+// directive or the WithCacheStaleWhileRevalidate option.
+//
+// Whether the response was served from the cache is added to the logctx fields of the request, so it is recommended
+// this comes _after_ the logctx middleware.
+func NewCacheMiddleware(store CacheStore, opts ...CacheOption) func(http.Handler) http.Handler {
+	c := &cache{
+		store: store,
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c.middleware
+}
+
+func (c *cache) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqDirectives := response.ParseCacheControl(strings.Join(r.Header.Values("Cache-Control"), ", "))
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || r.Header.Get("Authorization") != "" ||
+			reqDirectives.NoStore {
+			next.ServeHTTP(w, r.WithContext(addLogCtxString(r.Context(), "cache", "bypass")))
+			return
+		}
+
+		key := c.key(r)
+
+		// Serve from the cache
+		if !reqDirectives.NoCache {
+			if entry, ok := c.store.Get(key); ok {
+				age := c.now().Sub(entry.StoredAt)
+				acceptable := !reqDirectives.Has("max-age") || age <= reqDirectives.MaxAge
+				switch {
+				case acceptable && age <= entry.TTL:
+					addLogCtxString(r.Context(), "cache", "hit")
+					c.write(w, r, entry, age)
+					return
+				case acceptable && age <= entry.TTL+entry.StaleWhileRevalidate:
+					addLogCtxString(r.Context(), "cache", "stale")
+					c.write(w, r, entry, age)
+					if _, revalidating := c.revalidating.LoadOrStore(key, true); !revalidating {
+						// Detached while the request is in flight, as its route context is reused once complete
+						go c.revalidate(key, detachRequest(r), next)
+					}
+					return
+				}
+			}
+		}
+
+		if reqDirectives.OnlyIfCached {
+			addLogCtxString(r.Context(), "cache", "miss")
+			c.writeResponse(w, r, response.NewError(http.StatusGatewayTimeout).JsonResponse())
+			return
+		}
+
+		// Fetch the response, coalescing concurrent requests for the same key
+		r = r.WithContext(addLogCtxString(r.Context(), "cache", "miss"))
+		entry, storable, shared := c.group.do(key, func() (CacheEntry, bool) {
+			return c.fetch(key, r, next)
+		})
+		if shared && !storable {
+			// The response could not be shared between requests, so must be fetched again for this request
+			entry, _ = c.fetch(key, r, next)
+		}
+		c.write(w, r, entry, 0)
+	})
+}
+
+// key returns the cache key of the request, from the method, path, normalised query and vary headers.
+func (c *cache) key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteString(" ")
+	b.WriteString(r.URL.Path)
+	if q := r.URL.Query(); len(q) > 0 {
+		b.WriteString("?")
+		b.WriteString(q.Encode())
+	}
+	for _, h := range c.vary {
+		b.WriteString("\n")
+		b.WriteString(h)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(r.Header.Values(h), ", "))
+	}
+	return b.String()
+}
+
+// fetch calls the next handler, storing the response in the cache if it is storable.
+func (c *cache) fetch(key string, r *http.Request, next http.Handler) (CacheEntry, bool) {
+	rec := recorder.New()
+	next.ServeHTTP(rec, r)
+
+	entry := CacheEntry{
+		StatusCode: rec.StatusCode,
+		Header:     rec.HeaderMap,
+		Body:       rec.Body.Bytes(),
+		StoredAt:   c.now(),
+	}
+	if !c.storable(&entry) {
+		return entry, false
+	}
+	c.store.Set(key, entry)
+	return entry, true
+}
+
+// revalidate fetches a fresh response for a stale cache entry, with a request detached from the original request.
+func (c *cache) revalidate(key string, r *http.Request, next http.Handler) {
+	defer c.revalidating.Delete(key)
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			Log(r.Context(), zap.ErrorLevel, "Unable to revalidate cached response", zap.Any("panic", rvr))
+		}
+	}()
+	c.group.do(key, func() (CacheEntry, bool) {
+		return c.fetch(key, r, next)
+	})
+}
+
+// storable reports whether the response can be stored, setting the freshness lifetimes of the entry from the response
+// Cache-Control directives.
+func (c *cache) storable(entry *CacheEntry) bool {
+	if !slices.Contains(cacheableStatusCodes, entry.StatusCode) || len(entry.Header.Values("Set-Cookie")) > 0 {
+		return false
+	}
+
+	directives := response.ParseCacheControl(strings.Join(entry.Header.Values("Cache-Control"), ", "))
+	if directives.NoStore || directives.Private || directives.NoCache {
+		return false
+	}
+
+	for _, v := range entry.Header.Values("Vary") {
+		for _, h := range strings.Split(v, ",") {
+			h = http.CanonicalHeaderKey(strings.TrimSpace(h))
+			if h == "*" || !slices.Contains(c.vary, h) {
+				return false
+			}
+		}
+	}
+
+	entry.TTL = c.ttl
+	if directives.Has("s-maxage") {
+		entry.TTL = directives.SharedMaxAge
+	} else if directives.Has("max-age") {
+		entry.TTL = directives.MaxAge
+	}
+	entry.StaleWhileRevalidate = c.staleWhileRevalidate
+	if directives.Has("stale-while-revalidate") {
+		entry.StaleWhileRevalidate = directives.StaleWhileRevalidate
+	}
+	return entry.TTL > 0
+}
+
+// write replays a stored response to the response writer.
+func (c *cache) write(w http.ResponseWriter, r *http.Request, entry CacheEntry, age time.Duration) {
+	rec := &recorder.ResponseRecorder{StatusCode: entry.StatusCode, HeaderMap: entry.Header.Clone()}
+	if age > 0 {
+		rec.HeaderMap.Set("Age", strconv.Itoa(int(age.Seconds())))
+	}
+	if r.Method != http.MethodHead {
+		rec.Body.Write(entry.Body)
+	}
+	if err := rec.WriteTo(w); err != nil {
+		// Unable to write the response to the response writer
+		Log(r.Context(), zap.ErrorLevel, "Unable to write response", zap.Error(err))
+	}
+}
+
+func (c *cache) writeResponse(w http.ResponseWriter, r *http.Request, resp response.Response) {
+	if err := resp.WriteTo(w); err != nil {
+		// Unable to write the response to the response writer
+		Log(r.Context(), zap.ErrorLevel, "Unable to write response", zap.Error(err))
+	}
+}
+
+// detachRequest returns a copy of the request that can be used once the request is complete, detached from the lifetime
+// of its context. chi reuses the route context of a request once complete, so the copy has a copy of the route context.
+func detachRequest(r *http.Request) *http.Request {
+	ctx := context.WithoutCancel(r.Context())
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		detached := chi.NewRouteContext()
+		detached.Routes = rctx.Routes
+		detached.RoutePath = rctx.RoutePath
+		detached.RouteMethod = rctx.RouteMethod
+		detached.URLParams.Keys = slices.Clone(rctx.URLParams.Keys)
+		detached.URLParams.Values = slices.Clone(rctx.URLParams.Values)
+		detached.RoutePatterns = slices.Clone(rctx.RoutePatterns)
+		ctx = context.WithValue(ctx, chi.RouteCtxKey, detached)
+	}
+	return r.Clone(ctx)
+}
+
+// cacheFlightGroup coalesces concurrent fetches of the same cache key, so only one is in flight at a time.
+type cacheFlightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*cacheFlightCall
+}
+
+type cacheFlightCall struct {
+	wg       sync.WaitGroup
+	entry    CacheEntry
+	storable bool
+}
+
+// do calls fn, unless a call for the same key is already in flight, in which case it waits for and returns the result
+// of that call instead. Whether the result was shared with another caller is also returned.
+func (g *cacheFlightGroup) do(key string, fn func() (CacheEntry, bool)) (entry CacheEntry, storable bool, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*cacheFlightCall{}
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.entry, call.storable, true
+	}
+	call := &cacheFlightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	call.entry, call.storable = fn()
+	return call.entry, call.storable, false
+}
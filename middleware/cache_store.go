@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a complete response stored by the cache middleware.
+type CacheEntry struct {
+	StatusCode           int
+	Header               http.Header
+	Body                 []byte
+	StoredAt             time.Time
+	TTL                  time.Duration
+	StaleWhileRevalidate time.Duration
+}
+
+// CacheStore stores responses for the cache middleware. Implementations must be safe for concurrent use.
+type CacheStore interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, entry CacheEntry)
+	Delete(key string)
+}
+
+// MemoryCacheStore is an in-memory CacheStore that holds a limited number of entries, evicting the least recently used
+// entry when full.
+type MemoryCacheStore struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry CacheEntry
+}
+
+// NewMemoryCacheStore returns an in-memory CacheStore holding at most capacity entries.
+func NewMemoryCacheStore(capacity int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+func (s *MemoryCacheStore) Get(key string) (CacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	s.order.MoveToFront(el)
+	return el.Value.(*memoryCacheItem).entry, true
+}
+
+func (s *MemoryCacheStore) Set(key string, entry CacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*memoryCacheItem).entry = entry
+		s.order.MoveToFront(el)
+		return
+	}
+	s.entries[key] = s.order.PushFront(&memoryCacheItem{key: key, entry: entry})
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+func (s *MemoryCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.order.Remove(el)
+		delete(s.entries, key)
+	}
+}
+
+// Len returns the number of entries in the store.
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
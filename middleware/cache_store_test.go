@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestMemoryCacheStore(t *testing.T) {
+	entry := func(status int) CacheEntry {
+		return CacheEntry{StatusCode: status}
+	}
+
+	t.Run("Missing key returns false", func(t *testing.T) {
+		s := NewMemoryCacheStore(2)
+		_, ok := s.Get("a")
+		assert.False(t, ok)
+	})
+
+	t.Run("Stored entry is returned", func(t *testing.T) {
+		s := NewMemoryCacheStore(2)
+		s.Set("a", entry(http.StatusOK))
+		got, ok := s.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, entry(http.StatusOK), got)
+	})
+
+	t.Run("Stored entry is replaced", func(t *testing.T) {
+		s := NewMemoryCacheStore(2)
+		s.Set("a", entry(http.StatusOK))
+		s.Set("a", entry(http.StatusNotFound))
+		got, _ := s.Get("a")
+		assert.Equal(t, entry(http.StatusNotFound), got)
+		assert.Equal(t, 1, s.Len())
+	})
+
+	t.Run("Deleted entry is removed", func(t *testing.T) {
+		s := NewMemoryCacheStore(2)
+		s.Set("a", entry(http.StatusOK))
+		s.Delete("a")
+		_, ok := s.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, s.Len())
+	})
+
+	t.Run("Least recently used entry is evicted when full", func(t *testing.T) {
+		s := NewMemoryCacheStore(2)
+		s.Set("a", entry(http.StatusOK))
+		s.Set("b", entry(http.StatusOK))
+		s.Get("a")
+		s.Set("c", entry(http.StatusOK))
+
+		_, okA := s.Get("a")
+		_, okB := s.Get("b")
+		_, okC := s.Get("c")
+		assert.True(t, okA, "a")
+		assert.False(t, okB, "b")
+		assert.True(t, okC, "c")
+		assert.Equal(t, 2, s.Len())
+	})
+}
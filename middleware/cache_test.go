@@ -0,0 +1,376 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type cacheTestClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *cacheTestClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *cacheTestClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestCache(opts ...CacheOption) (func(http.Handler) http.Handler, *cacheTestClock) {
+	clock := &cacheTestClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	c := &cache{store: NewMemoryCacheStore(10), now: clock.Now}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c.middleware, clock
+}
+
+func TestNewCacheMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []CacheOption
+		cacheControl  string
+		requests      []*http.Request
+		wantBodies    []string
+		wantNextCalls int32
+	}{
+		{
+			name:         "Fresh response is served from the cache",
+			cacheControl: "max-age=60",
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+			},
+			wantBodies:    []string{"response 1", "response 1"},
+			wantNextCalls: 1,
+		},
+		{
+			name: "Response without freshness is not stored",
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+			},
+			wantBodies:    []string{"response 1", "response 2"},
+			wantNextCalls: 2,
+		},
+		{
+			name: "Default TTL used when response has no freshness",
+			opts: []CacheOption{WithCacheTTL(time.Minute)},
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+			},
+			wantBodies:    []string{"response 1", "response 1"},
+			wantNextCalls: 1,
+		},
+		{
+			name:         "Response with no-store is not stored",
+			opts:         []CacheOption{WithCacheTTL(time.Minute)},
+			cacheControl: "no-store",
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+			},
+			wantBodies:    []string{"response 1", "response 2"},
+			wantNextCalls: 2,
+		},
+		{
+			name:         "Private response is not stored",
+			cacheControl: "private, max-age=60",
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+			},
+			wantBodies:    []string{"response 1", "response 2"},
+			wantNextCalls: 2,
+		},
+		{
+			name:         "Query parameter order does not affect the cache key",
+			cacheControl: "max-age=60",
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/test?b=2&a=1", nil),
+				httptest.NewRequest(http.MethodGet, "/test?a=1&b=2", nil),
+			},
+			wantBodies:    []string{"response 1", "response 1"},
+			wantNextCalls: 1,
+		},
+		{
+			name:         "Different query is a different cache key",
+			cacheControl: "max-age=60",
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/test?a=1", nil),
+				httptest.NewRequest(http.MethodGet, "/test?a=2", nil),
+			},
+			wantBodies:    []string{"response 1", "response 2"},
+			wantNextCalls: 2,
+		},
+		{
+			name:         "Non-GET requests bypass the cache",
+			cacheControl: "max-age=60",
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodPost, "/test", nil),
+				httptest.NewRequest(http.MethodPost, "/test", nil),
+			},
+			wantBodies:    []string{"response 1", "response 2"},
+			wantNextCalls: 2,
+		},
+		{
+			name:         "Request with no-cache is not served from the cache",
+			cacheControl: "max-age=60",
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+				withHeader(httptest.NewRequest(http.MethodGet, "/test", nil), "Cache-Control", "no-cache"),
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+			},
+			wantBodies:    []string{"response 1", "response 2", "response 2"},
+			wantNextCalls: 2,
+		},
+		{
+			name:         "Request with Authorization bypasses the cache",
+			cacheControl: "max-age=60",
+			requests: []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/test", nil),
+				withHeader(httptest.NewRequest(http.MethodGet, "/test", nil), "Authorization", "Bearer abc"),
+			},
+			wantBodies:    []string{"response 1", "response 2"},
+			wantNextCalls: 2,
+		},
+		{
+			name:         "Vary headers are included in the cache key",
+			opts:         []CacheOption{WithCacheVary("accept-language")},
+			cacheControl: "max-age=60",
+			requests: []*http.Request{
+				withHeader(httptest.NewRequest(http.MethodGet, "/test", nil), "Accept-Language", "en"),
+				withHeader(httptest.NewRequest(http.MethodGet, "/test", nil), "Accept-Language", "fr"),
+				withHeader(httptest.NewRequest(http.MethodGet, "/test", nil), "Accept-Language", "en"),
+			},
+			wantBodies:    []string{"response 1", "response 2", "response 1"},
+			wantNextCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut, _ := newTestCache(tt.opts...)
+
+			var calls atomic.Int32
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				if tt.cacheControl != "" {
+					w.Header().Set("Cache-Control", tt.cacheControl)
+				}
+				_, _ = w.Write([]byte("response " + string('0'+n)))
+			})
+
+			for i, r := range tt.requests {
+				w := httptest.NewRecorder()
+				sut(next).ServeHTTP(w, r)
+				assert.Equalf(t, tt.wantBodies[i], w.Body.String(), "request %d body", i+1)
+			}
+			assert.Equalf(t, tt.wantNextCalls, calls.Load(), "next calls")
+		})
+	}
+}
+
+func TestNewCacheMiddleware_Expiry(t *testing.T) {
+	sut, clock := newTestCache()
+
+	var calls atomic.Int32
+	revalidated := make(chan struct{}, 1)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=30")
+		_, _ = w.Write([]byte("response " + string('0'+n)))
+		if n > 1 {
+			revalidated <- struct{}{}
+		}
+	})
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		sut(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+		return w
+	}
+
+	assert.Equal(t, "response 1", get().Body.String())
+
+	// Fresh
+	clock.Advance(30 * time.Second)
+	w := get()
+	assert.Equal(t, "response 1", w.Body.String())
+	assert.Equal(t, "30", w.Header().Get("Age"))
+
+	// Stale, served while revalidated
+	clock.Advance(45 * time.Second)
+	assert.Equal(t, "response 1", get().Body.String())
+	select {
+	case <-revalidated:
+	case <-time.After(time.Second):
+		t.Fatal("response was not revalidated")
+	}
+	assert.Eventually(t, func() bool {
+		return get().Body.String() == "response 2"
+	}, time.Second, 10*time.Millisecond)
+
+	// Expired beyond stale-while-revalidate
+	clock.Advance(2 * time.Minute)
+	assert.Equal(t, "response 3", get().Body.String())
+}
+
+func TestNewCacheMiddleware_OnlyIfCached(t *testing.T) {
+	sut, _ := newTestCache()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next should not be called")
+	})
+
+	w := httptest.NewRecorder()
+	sut(next).ServeHTTP(w, withHeader(httptest.NewRequest(http.MethodGet, "/test", nil), "Cache-Control", "only-if-cached"))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestNewCacheMiddleware_Coalescing(t *testing.T) {
+	sut, _ := newTestCache()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("response"))
+	})
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 5)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			sut(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+			bodies[i] = w.Body.String()
+		}(i)
+	}
+
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, []string{"response", "response", "response", "response", "response"}, bodies)
+}
+
+func withHeader(r *http.Request, key, value string) *http.Request {
+	r.Header.Set(key, value)
+	return r
+}
+
+func TestNewCacheMiddleware_RevalidateRouter(t *testing.T) {
+	sut, clock := newTestCache()
+
+	revalidated := make(chan string, 10)
+	var calls sync.Map
+	router := chi.NewRouter()
+	router.Use(sut)
+	router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if _, loaded := calls.LoadOrStore(id, true); loaded {
+			revalidated <- id
+		}
+		w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=30")
+		_, _ = w.Write([]byte("item " + id))
+	})
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "item 1", get("/items/1"))
+	clock.Advance(75 * time.Second)
+
+	// The stale hit is revalidated while other requests reuse the route context of the original request
+	var wg sync.WaitGroup
+	assert.Equal(t, "item 1", get("/items/1"))
+	for i := 2; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			get(fmt.Sprintf("/items/%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	select {
+	case id := <-revalidated:
+		assert.Equal(t, "1", id)
+	case <-time.After(time.Second):
+		t.Fatal("response was not revalidated")
+	}
+}
+
+func TestNewCacheMiddleware_RevalidateOnce(t *testing.T) {
+	sut, clock := newTestCache()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) > 1 {
+			<-release
+		}
+		w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=30")
+		_, _ = w.Write([]byte("response"))
+	})
+	get := func() string {
+		w := httptest.NewRecorder()
+		sut(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+		return w.Body.String()
+	}
+
+	get()
+	clock.Advance(75 * time.Second)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, "response", get())
+	}
+	assert.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(2), calls.Load())
+	close(release)
+}
+
+func TestNewCacheMiddleware_RequestMaxAge(t *testing.T) {
+	sut, clock := newTestCache()
+
+	var calls atomic.Int32
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("response " + string('0'+n)))
+	})
+	get := func(cacheControl string) string {
+		w := httptest.NewRecorder()
+		sut(next).ServeHTTP(w, withHeader(httptest.NewRequest(http.MethodGet, "/test", nil), "Cache-Control", cacheControl))
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "response 1", get(""))
+	clock.Advance(10 * time.Second)
+	// Malformed max-age is ignored
+	assert.Equal(t, "response 1", get("max-age=abc"))
+	assert.Equal(t, "response 1", get("max-age=30"))
+	assert.Equal(t, "response 2", get("max-age=5"))
+	clock.Advance(time.Second)
+	assert.Equal(t, "response 3", get("max-age=0"))
+}
@@ -1,57 +1,159 @@
 package middleware
 
 import (
+	"context"
 	"github.com/ellogroup/ello-golang-ctx/logctx"
+	"github.com/go-chi/chi/v5"
 	chi_middleware "github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// redacted replaces the value of redacted query parameters, path segments, headers and body values in request logs.
+const redacted = "REDACTED"
+
+type logCtxRequestKey struct{}
+
+// logCtxRequest holds the log context of a request being processed by the logctx middleware, so that fields added
+// further down the middleware chain can also be included in the completion log entry.
+type logCtxRequest struct {
+	mu  sync.Mutex
+	ctx context.Context
+	// log is the logger of the middleware, used by Log when no other logger is set in the context
+	log requestLogger
+}
+
 // NewLogCtxMiddleware returns a handler to be used as middleware. This middleware will add details of the request to
 // the context of the request using github.com/ellogroup/ello-golang-ctx/logctx. This context can then be used to enrich
 // log entries with the details of the request. Once the request is complete, the details of the completed request will
-// also be logged out.
+// also be logged out, including the matched chi route pattern (e.g. `/orders/{id}`).
+//
+// The path and query of the request are logged separately, as well as in the request URI. Options can redact sensitive
+// query parameters and path segments, or omit the query entirely.
+//
+// Options can also choose the fields captured, including request and response headers, set the level of the completion
+// log entry by status class, and skip the start log entry or the log entries of specific paths. Log entries can also be
+// sampled by route, status class and duration, with the number of requests dropped reported periodically. Request and
+// response bodies can be included in the completion log entry, with sensitive values redacted, and a warning log entry
+// written for slow requests.
 //
 // If used, it is recommended this is one of the first middleware in the chain so all following processes have access
 // to the request details. However, the request id middleware should always come _before_ this middleware.
-func NewLogCtxMiddleware(log *zap.Logger) func(http.Handler) http.Handler {
+func NewLogCtxMiddleware(log *zap.Logger, opts ...LogCtxOption) func(http.Handler) http.Handler {
+	return newLogCtxMiddleware(zapRequestLogger{log}, opts)
+}
+
+// NewSlogLogCtxMiddleware returns a handler to be used as middleware, the same as NewLogCtxMiddleware but writing the
+// log entries to a log/slog logger. Levels set with WithLogCtxLevel are converted to the equivalent slog levels.
+func NewSlogLogCtxMiddleware(log *slog.Logger, opts ...LogCtxOption) func(http.Handler) http.Handler {
+	return newLogCtxMiddleware(slogRequestLogger{log}, opts)
+}
+
+func newLogCtxMiddleware(log requestLogger, opts []LogCtxOption) func(http.Handler) http.Handler {
+	c := newLogCtxConfig(opts)
+	if c.sampler.reporter != nil {
+		c.sampler.reporter.start(c.sampler, log)
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Add attributes to context
-			ctx := logctx.Add(
-				r.Context(),
-				logctx.String("http_proto", r.Proto),
-				logctx.String("http_method", r.Method),
-				logctx.String("request_uri", r.RequestURI),
-				logctx.String("remote_addr", r.RemoteAddr),
-				logctx.String("user_agent", r.UserAgent()),
-			)
-
-			// Add request id to logger context
-			if requestId := RequestId(r.Context()); requestId != "" {
-				ctx = logctx.Add(ctx, logctx.String("request_id", requestId))
+			ctx := c.addRequestFields(r.Context(), r)
+			sampled, dropped := c.sampled(r)
+			requestStart := time.Now()
+
+			// Log request info, unless deferred until the request is complete as it may be dropped by a sampling rule
+			startCtx := ctx
+			deferStart := c.sampler.enabled() && !c.sampler.kept(RequestId(r.Context()))
+			if sampled && !c.skipStart && !deferStart {
+				log.Log(ctx, time.Time{}, zap.InfoLevel, "Request started", logctx.Zap(ctx)...)
 			}
 
-			// Log request info
-			log.Info("Request started", logctx.Zap(ctx)...)
+			// Allow fields to be added to the completion log entry
+			req := &logCtxRequest{ctx: ctx, log: log}
+			ctx = context.WithValue(ctx, logCtxRequestKey{}, req)
 
 			// Wrap the response writer, so we can access details of the response, such as status code
 			ww := chi_middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
+			// Capture the request and response bodies as they are read and written, if logged
+			var requestBody, responseBody *bodyCapture
+			if sampled && c.bodyMaxSize > 0 {
+				if r.Body != nil && r.Body != http.NoBody && c.logsBody(r.Header.Get("Content-Type")) {
+					requestBody = &bodyCapture{max: c.bodyMaxSize}
+					r.Body = &captureReader{ReadCloser: r.Body, capture: requestBody}
+				}
+				responseBody = &bodyCapture{max: c.bodyMaxSize}
+			}
+
+			// Record the times of the writes of the response body for slow requests. The tee is only installed when
+			// needed, as it stops the response writer passing io.ReaderFrom through to the underlying writer
+			var writes *responseWrites
+			if responseBody != nil || c.slow.enabled() {
+				writes = &responseWrites{start: requestStart, body: responseBody}
+				ww.Tee(writes)
+			}
+
+			// Capture the stack of slow requests while in flight, if enabled
+			var stack *stackCapture
+			if threshold := c.slow.stackThreshold(); threshold > 0 {
+				stack = captureStackAfter(threshold)
+			}
+
 			// Log the response details
-			requestStart := time.Now()
 			defer func() {
+				defer c.sampler.report(log)
+				status := responseStatus(ww)
+				duration := time.Since(requestStart)
+				// The route pattern is only known once the request has been routed
+				var route string
+				if rctx := chi.RouteContext(r.Context()); rctx != nil {
+					route = rctx.RoutePattern()
+				}
+				c.logSlow(log, req.context(), route, status, duration, writes, stack.stop())
+
+				if !sampled {
+					if dropped {
+						c.sampler.drop()
+					}
+					return
+				}
+
+				if c.sampler.enabled() {
+					if !c.sampler.keep(RequestId(r.Context()), route, status, duration) {
+						c.sampler.drop()
+						return
+					}
+					if deferStart && !c.skipStart {
+						log.Log(startCtx, requestStart, zap.InfoLevel, "Request started", logctx.Zap(startCtx)...)
+					}
+				}
+
+				fields := []zap.Field{
+					zap.Int("status_code", status),
+					zap.Int("bytes_written", ww.BytesWritten()),
+					zap.Duration("duration", duration),
+				}
+				if c.has(LogCtxRoute) && route != "" {
+					fields = append(fields, zap.String(string(LogCtxRoute), route))
+				}
+				for _, h := range c.responseHeaders {
+					if v := ww.Header().Get(h); v != "" {
+						fields = append(fields, zap.String(headerField("response_header_", h), c.headerValue(h, v)))
+					}
+				}
+				fields = append(fields, c.bodyFields("request", r.Header.Get("Content-Type"), requestBody)...)
+				fields = append(fields, c.bodyFields("response", ww.Header().Get("Content-Type"), responseBody)...)
+
 				// Log response info
-				log.Info(
-					"Request complete",
-					logctx.Zap(
-						ctx,
-						zap.Int("status_code", ww.Status()),
-						zap.Int("bytes_written", ww.BytesWritten()),
-						zap.Duration("duration", time.Since(requestStart)),
-					)...,
-				)
+				ctx := req.context()
+				log.Log(ctx, time.Time{}, c.level(status), "Request complete", logctx.Zap(ctx, fields...)...)
 			}()
 
 			// Call the next handler in the chain, passing the response writer and
@@ -60,3 +162,107 @@ func NewLogCtxMiddleware(log *zap.Logger) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// addRequestFields adds the configured fields of the request to the log context.
+func (c *logCtxConfig) addRequestFields(ctx context.Context, r *http.Request) context.Context {
+	path, query := c.path(r), c.query(r)
+	values := map[LogCtxField]string{
+		LogCtxProto:      r.Proto,
+		LogCtxMethod:     r.Method,
+		LogCtxRequestURI: c.requestURI(r, path, query),
+		LogCtxPath:       path,
+		LogCtxQuery:      query,
+		LogCtxRemoteAddr: r.RemoteAddr,
+		LogCtxUserAgent:  r.UserAgent(),
+		LogCtxRequestId:  RequestId(r.Context()),
+		LogCtxHost:       r.Host,
+		LogCtxScheme:     "http",
+	}
+	if r.TLS != nil {
+		values[LogCtxScheme] = "https"
+	}
+	if r.ContentLength >= 0 {
+		values[LogCtxContentLength] = strconv.FormatInt(r.ContentLength, 10)
+	}
+
+	for _, f := range c.fields {
+		v := values[f]
+		// Optional fields are only added when set
+		if v == "" && (f == LogCtxQuery || f == LogCtxRequestId || f == LogCtxContentLength) {
+			continue
+		}
+		if f != LogCtxRoute {
+			ctx = logctx.Add(ctx, logctx.String(string(f), v))
+		}
+	}
+	for _, h := range c.requestHeaders {
+		if v := r.Header.Get(h); v != "" {
+			ctx = logctx.Add(ctx, logctx.String(headerField("request_header_", h), c.headerValue(h, v)))
+		}
+	}
+	return ctx
+}
+
+// requestURI returns the request URI to log, which is only rebuilt from the path and query if they may be redacted.
+func (c *logCtxConfig) requestURI(r *http.Request, path, query string) string {
+	if len(c.redactQuery) == 0 && len(c.redactPath) == 0 && !c.omitQuery {
+		return r.RequestURI
+	}
+	if query == "" {
+		return path
+	}
+	return path + "?" + query
+}
+
+// path returns the escaped path of the request, with any matching path segments redacted.
+func (c *logCtxConfig) path(r *http.Request) string {
+	path := r.URL.EscapedPath()
+	if len(c.redactPath) == 0 {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			unescaped = segment
+		}
+		for _, re := range c.redactPath {
+			if segment != "" && re.MatchString(unescaped) {
+				segments[i] = redacted
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// query returns the raw query of the request, with the values of any sensitive parameters redacted. The order and
+// encoding of the other parameters are unchanged.
+func (c *logCtxConfig) query(r *http.Request) string {
+	if c.omitQuery {
+		return ""
+	}
+	if len(c.redactQuery) == 0 {
+		return r.URL.RawQuery
+	}
+	return redactParams(r.URL.RawQuery, func(name, _ string) bool {
+		return slices.Contains(c.redactQuery, strings.ToLower(name))
+	})
+}
+
+func (l *logCtxRequest) context() context.Context {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ctx
+}
+
+// addLogCtxString adds a string field to the log context of the request. If the request is being processed by the
+// logctx middleware, the field will also be included in the completion log entry.
+func addLogCtxString(ctx context.Context, key, value string) context.Context {
+	if req, ok := ctx.Value(logCtxRequestKey{}).(*logCtxRequest); ok {
+		req.mu.Lock()
+		req.ctx = logctx.Add(req.ctx, logctx.String(key, value))
+		req.mu.Unlock()
+	}
+	return logctx.Add(ctx, logctx.String(key, value))
+}
@@ -0,0 +1,297 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultLogCtxRedactKeys match the keys of JSON bodies and form parameters redacted in request logs.
+var DefaultLogCtxRedactKeys = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)password|passwd|secret|token|api_?key|authori[sz]ation|cookie|card_?number|cvv|cvc`),
+}
+
+// DefaultLogCtxRedactHeaders are the request and response headers redacted in request logs.
+var DefaultLogCtxRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// WithLogCtxBodies includes the request and response bodies in the completion log entry, for the given content types,
+// e.g. `application/json` or `text/*`, defaulting to `application/json`. Bodies are captured up to maxSize bytes as
+// they are read by the handler and written to the response, so streaming is unaffected.
+//
+// The values of keys matching DefaultLogCtxRedactKeys, and values that look like card numbers, are redacted from JSON
+// and form bodies. Bodies of other content types are logged as is.
+func WithLogCtxBodies(maxSize int, contentTypes ...string) LogCtxOption {
+	return func(c *logCtxConfig) {
+		if len(contentTypes) == 0 {
+			contentTypes = []string{"application/json"}
+		}
+		c.bodyMaxSize = maxSize
+		c.bodyContentTypes = contentTypes
+	}
+}
+
+// WithLogCtxRedactKeys redacts the values of the keys of JSON bodies and form parameters matching any of the patterns,
+// in addition to DefaultLogCtxRedactKeys.
+func WithLogCtxRedactKeys(patterns ...*regexp.Regexp) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.redactKeys = append(c.redactKeys, patterns...)
+	}
+}
+
+// WithLogCtxRedactHeaders redacts the values of the given headers in request logs, in addition to
+// DefaultLogCtxRedactHeaders.
+func WithLogCtxRedactHeaders(headers ...string) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.redactHeaders = append(c.redactHeaders, headers...)
+	}
+}
+
+// headerValue returns the value of a header to log, redacted if sensitive.
+func (c *logCtxConfig) headerValue(header, value string) string {
+	if slices.ContainsFunc(c.redactHeaders, func(h string) bool { return strings.EqualFold(h, header) }) {
+		return redacted
+	}
+	return value
+}
+
+// logsBody returns whether a body of the content type is logged.
+func (c *logCtxConfig) logsBody(contentType string) bool {
+	if c.bodyMaxSize <= 0 {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range c.bodyContentTypes {
+		t = strings.ToLower(t)
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyFields returns the fields of a captured body to include in the completion log entry.
+func (c *logCtxConfig) bodyFields(prefix, contentType string, body *bodyCapture) []zap.Field {
+	if body == nil || body.n == 0 || !c.logsBody(contentType) {
+		return nil
+	}
+	fields := []zap.Field{zap.String(prefix+"_body", c.body(contentType, body))}
+	if body.Truncated() {
+		fields = append(fields, zap.Bool(prefix+"_body_truncated", true))
+	}
+	return fields
+}
+
+// body returns the body to log, with sensitive values redacted.
+func (c *logCtxConfig) body(contentType string, body *bodyCapture) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return redactJSON(body.Bytes(), c.redactKey)
+	case mediaType == "application/x-www-form-urlencoded":
+		return redactParams(body.String(), func(name, value string) bool {
+			return c.redactKey(name) || isCardNumber(value)
+		})
+	}
+	return body.String()
+}
+
+func (c *logCtxConfig) redactKey(key string) bool {
+	return slices.ContainsFunc(c.redactKeys, func(re *regexp.Regexp) bool { return re.MatchString(key) })
+}
+
+// bodyCapture captures a body up to a maximum size, counting the bytes beyond it. Writes never fail, so it can be used
+// as the tee of a response writer.
+type bodyCapture struct {
+	bytes.Buffer
+	max int
+	n   int
+}
+
+func (b *bodyCapture) Write(p []byte) (int, error) {
+	b.n += len(p)
+	if remaining := b.max - b.Len(); remaining > 0 {
+		b.Buffer.Write(p[:min(remaining, len(p))])
+	}
+	return len(p), nil
+}
+
+// Truncated returns whether the body was larger than the maximum size.
+func (b *bodyCapture) Truncated() bool {
+	return b.n > b.max
+}
+
+// captureReader captures a request body as it is read.
+type captureReader struct {
+	io.ReadCloser
+	capture *bodyCapture
+}
+
+func (c *captureReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	_, _ = c.capture.Write(p[:n])
+	return n, err
+}
+
+// jsonFrame is an object or array being redacted by redactJSON.
+type jsonFrame struct {
+	object bool
+	// n is the number of elements or keys written
+	n int
+	// value is whether the next token of an object is a value rather than a key
+	value bool
+}
+
+// redactJSON returns compacted JSON with the values of matching keys, and values that look like card numbers, redacted.
+// Invalid or truncated JSON is redacted up to the point it can no longer be parsed, followed by `...`.
+func redactJSON(data []byte, redactKey func(string) bool) string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var b strings.Builder
+	var stack []*jsonFrame
+	// skip is the depth of an object or array being skipped, as its value is redacted
+	skip := 0
+	redactValue := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF && len(stack) == 0 && skip == 0 {
+			return b.String()
+		}
+		if err != nil {
+			return b.String() + "..."
+		}
+		delim, isDelim := tok.(json.Delim)
+		if skip > 0 {
+			if delim == '{' || delim == '[' {
+				skip++
+			} else if delim == '}' || delim == ']' {
+				skip--
+			}
+			continue
+		}
+		if delim == '}' || delim == ']' {
+			stack = stack[:len(stack)-1]
+			b.WriteRune(rune(delim))
+			continue
+		}
+
+		if len(stack) == 0 && b.Len() > 0 {
+			// Stream of values
+			b.WriteByte('\n')
+		}
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if top.object && !top.value {
+				// Key of an object, which is always a string
+				key, _ := tok.(string)
+				if top.n > 0 {
+					b.WriteByte(',')
+				}
+				top.n++
+				top.value = true
+				writeJSONValue(&b, key)
+				b.WriteByte(':')
+				redactValue = redactKey(key)
+				continue
+			}
+			if top.object {
+				top.value = false
+			} else {
+				if top.n > 0 {
+					b.WriteByte(',')
+				}
+				top.n++
+			}
+		}
+
+		switch {
+		case redactValue || isCardNumber(tok):
+			redactValue = false
+			writeJSONValue(&b, redacted)
+			if isDelim {
+				skip = 1
+			}
+		case isDelim:
+			stack = append(stack, &jsonFrame{object: delim == '{'})
+			b.WriteRune(rune(delim))
+		default:
+			writeJSONValue(&b, tok)
+		}
+	}
+}
+
+func writeJSONValue(b *strings.Builder, v any) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	b.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// redactParams returns URL encoded parameters with the values of matching parameters redacted. The order and encoding
+// of the other parameters are unchanged.
+func redactParams(raw string, redact func(name, value string) bool) string {
+	params := strings.Split(raw, "&")
+	for i, param := range params {
+		key, value, hasValue := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		v, err := url.QueryUnescape(value)
+		if err != nil {
+			v = value
+		}
+		if hasValue && redact(name, v) {
+			params[i] = key + "=" + redacted
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// isCardNumber returns whether a JSON token looks like a payment card number, 13 to 19 digits optionally separated by
+// spaces or dashes, passing the Luhn check.
+func isCardNumber(tok any) bool {
+	var s string
+	switch v := tok.(type) {
+	case string:
+		s = v
+	case json.Number:
+		s = v.String()
+	default:
+		return false
+	}
+	var digits []byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c-'0')
+		case c == ' ' || c == '-':
+		default:
+			return false
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i])
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
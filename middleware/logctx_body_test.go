@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewLogCtxMiddleware_Bodies(t *testing.T) {
+	tests := []struct {
+		name                string
+		opts                []LogCtxOption
+		requestContentType  string
+		requestBody         string
+		responseContentType string
+		responseBody        string
+		wantFields          map[string]any
+		wantMissing         []string
+	}{
+		{
+			name:                "Bodies are not logged by default",
+			requestContentType:  "application/json",
+			requestBody:         `{"name":"a"}`,
+			responseContentType: "application/json",
+			responseBody:        `{"id":1}`,
+			wantMissing:         []string{"request_body", "response_body"},
+		},
+		{
+			name:                "JSON bodies are logged with sensitive values redacted",
+			opts:                []LogCtxOption{WithLogCtxBodies(1024)},
+			requestContentType:  "application/json; charset=utf-8",
+			requestBody:         `{"name": "a", "password": "secret", "card": {"number": "4111 1111 1111 1111"}}`,
+			responseContentType: "application/json",
+			responseBody:        `{"id":1,"accessToken":{"value":"abc"},"items":[1,2]}`,
+			wantFields: map[string]any{
+				"request_body":  `{"name":"a","password":"REDACTED","card":{"number":"REDACTED"}}`,
+				"response_body": `{"id":1,"accessToken":"REDACTED","items":[1,2]}`,
+			},
+			wantMissing: []string{"request_body_truncated", "response_body_truncated"},
+		},
+		{
+			name:                "Bodies of other content types are not logged",
+			opts:                []LogCtxOption{WithLogCtxBodies(1024)},
+			requestContentType:  "text/plain",
+			requestBody:         "hello",
+			responseContentType: "application/octet-stream",
+			responseBody:        "binary",
+			wantMissing:         []string{"request_body", "response_body"},
+		},
+		{
+			name:                "Configured content types are logged",
+			opts:                []LogCtxOption{WithLogCtxBodies(1024, "text/*", "application/x-www-form-urlencoded")},
+			requestContentType:  "application/x-www-form-urlencoded",
+			requestBody:         "name=a&Password=secret&pan=4111-1111-1111-1111",
+			responseContentType: "text/plain; charset=utf-8",
+			responseBody:        "password=hello",
+			wantFields: map[string]any{
+				"request_body":  "name=a&Password=REDACTED&pan=REDACTED",
+				"response_body": "password=hello",
+			},
+		},
+		{
+			name:                "Bodies are truncated",
+			opts:                []LogCtxOption{WithLogCtxBodies(20)},
+			requestContentType:  "application/json",
+			requestBody:         `{"name":"a","token":"abc","other":"b"}`,
+			responseContentType: "application/json",
+			responseBody:        `{"id":1}`,
+			wantFields: map[string]any{
+				"request_body":           `{"name":"a","token":...`,
+				"request_body_truncated": true,
+				"response_body":          `{"id":1}`,
+			},
+			wantMissing: []string{"response_body_truncated"},
+		},
+		{
+			name:                "Custom keys are redacted",
+			opts:                []LogCtxOption{WithLogCtxBodies(1024), WithLogCtxRedactKeys(regexp.MustCompile(`^email$`))},
+			requestContentType:  "application/json",
+			requestBody:         `[{"email":"a@b.com","secret":null}]`,
+			responseContentType: "application/json",
+			wantFields: map[string]any{
+				"request_body": `[{"email":"REDACTED","secret":"REDACTED"}]`,
+			},
+			wantMissing: []string{"response_body"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+
+			router := chi.NewRouter()
+			router.Use(NewLogCtxMiddleware(zap.New(core), tt.opts...))
+			router.Post("/orders", func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, tt.requestBody, string(body))
+				w.Header().Set("Content-Type", tt.responseContentType)
+				_, _ = w.Write([]byte(tt.responseBody))
+			})
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(tt.requestBody))
+			req.Header.Set("Content-Type", tt.requestContentType)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tt.responseBody, rec.Body.String())
+
+			entries := logs.FilterMessage("Request complete").AllUntimed()
+			if !assert.Len(t, entries, 1) {
+				return
+			}
+			fields := entries[0].ContextMap()
+			for k, v := range tt.wantFields {
+				assert.Equalf(t, v, fields[k], "field %s", k)
+			}
+			for _, k := range tt.wantMissing {
+				assert.NotContainsf(t, fields, k, "field %s", k)
+			}
+		})
+	}
+}
+
+func TestNewLogCtxMiddleware_RedactHeaders(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	router := chi.NewRouter()
+	router.Use(NewLogCtxMiddleware(
+		zap.New(core),
+		WithLogCtxRequestHeaders("Authorization", "Cookie", "X-Api-Key", "X-Tenant-Id"),
+		WithLogCtxResponseHeaders("Set-Cookie", "Cache-Control"),
+		WithLogCtxRedactHeaders("x-api-key"),
+	))
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set("Cache-Control", "no-store")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set("Cookie", "session=abc")
+	req.Header.Set("X-Api-Key", "abc")
+	req.Header.Set("X-Tenant-Id", "tenant")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.FilterMessage("Request complete").AllUntimed()
+	if !assert.Len(t, entries, 1) {
+		return
+	}
+	assert.Subset(t, entries[0].ContextMap(), map[string]any{
+		"request_header_authorization":  "REDACTED",
+		"request_header_cookie":         "REDACTED",
+		"request_header_x_api_key":      "REDACTED",
+		"request_header_x_tenant_id":    "tenant",
+		"response_header_set_cookie":    "REDACTED",
+		"response_header_cache_control": "no-store",
+	})
+}
+
+func TestRedactJSON(t *testing.T) {
+	redactKey := func(key string) bool { return key == "secret" }
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"Scalars", `"a"`, `"a"`},
+		{
+			name: "Nested",
+			data: `{"a":{"b":[{"secret":1},{"c":"<d>"}]},"e":true}`,
+			want: `{"a":{"b":[{"secret":"REDACTED"},{"c":"<d>"}]},"e":true}`,
+		},
+		{"Redacted object", `{"secret":{"a":[1,{"b":2}]},"c":3}`, `{"secret":"REDACTED","c":3}`},
+		{
+			name: "Card number",
+			data: `{"a":4111111111111111,"b":"4111111111111112","c":[5555555555554444]}`,
+			want: `{"a":"REDACTED","b":"4111111111111112","c":["REDACTED"]}`,
+		},
+		{"Large numbers are preserved", `{"a":12345678901234567890123}`, `{"a":12345678901234567890123}`},
+		{"Stream", `{"a":1} {"secret":2}`, "{\"a\":1}\n{\"secret\":\"REDACTED\"}"},
+		{"Invalid", `{"a":1,}`, `{"a":1...`},
+		{"Truncated", `{"a":[1,2`, `{"a":[1,2...`},
+		{"Truncated redacted", `{"secret":{"a":1`, `{"secret":"REDACTED"...`},
+		{"Empty", ``, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, redactJSON([]byte(tt.data), redactKey))
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// LogCtxField is a field of the request that can be captured by the logctx middleware.
+type LogCtxField string
+
+const (
+	LogCtxProto         LogCtxField = "http_proto"
+	LogCtxMethod        LogCtxField = "http_method"
+	LogCtxRequestURI    LogCtxField = "request_uri"
+	LogCtxPath          LogCtxField = "path"
+	LogCtxQuery         LogCtxField = "query"
+	LogCtxRemoteAddr    LogCtxField = "remote_addr"
+	LogCtxUserAgent     LogCtxField = "user_agent"
+	LogCtxRequestId     LogCtxField = "request_id"
+	LogCtxHost          LogCtxField = "host"
+	LogCtxScheme        LogCtxField = "scheme"
+	LogCtxContentLength LogCtxField = "content_length"
+	// LogCtxRoute is the matched chi route pattern, only included in the completion log entry
+	LogCtxRoute LogCtxField = "route"
+)
+
+// DefaultLogCtxFields are the fields captured by the logctx middleware by default.
+var DefaultLogCtxFields = []LogCtxField{
+	LogCtxProto,
+	LogCtxMethod,
+	LogCtxRequestURI,
+	LogCtxPath,
+	LogCtxQuery,
+	LogCtxRemoteAddr,
+	LogCtxUserAgent,
+	LogCtxRequestId,
+	LogCtxRoute,
+}
+
+// LogCtxOption configures the logctx middleware.
+type LogCtxOption func(*logCtxConfig)
+
+// WithLogCtxFields sets the fields of the request captured by the logctx middleware, replacing DefaultLogCtxFields.
+// The status code, bytes written and duration are always included in the completion log entry.
+func WithLogCtxFields(fields ...LogCtxField) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.fields = fields
+	}
+}
+
+// WithLogCtxRequestHeaders captures the given request headers, e.g. `X-Tenant-Id` as `request_header_x_tenant_id`.
+func WithLogCtxRequestHeaders(headers ...string) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.requestHeaders = append(c.requestHeaders, headers...)
+	}
+}
+
+// WithLogCtxResponseHeaders includes the given response headers in the completion log entry, e.g. `Cache-Control` as
+// `response_header_cache_control`.
+func WithLogCtxResponseHeaders(headers ...string) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.responseHeaders = append(c.responseHeaders, headers...)
+	}
+}
+
+// WithLogCtxLevel sets the level of the completion log entry for a status class, the first digit of the status code,
+// e.g. WithLogCtxLevel(5, zap.ErrorLevel) for 5xx responses. Completion log entries are at info level by default.
+func WithLogCtxLevel(statusClass int, level zapcore.Level) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.levels[statusClass] = level
+	}
+}
+
+// WithLogCtxSkipStart skips the log entry when a request is started, so only the completion log entry is written.
+func WithLogCtxSkipStart() LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.skipStart = true
+	}
+}
+
+// WithLogCtxSkipPaths skips the log entries of requests to the given paths, e.g. health checks. The fields of the
+// request are still added to the context.
+func WithLogCtxSkipPaths(paths ...string) LogCtxOption {
+	return WithLogCtxSamplePaths(0, paths...)
+}
+
+// WithLogCtxSamplePaths only writes the log entries of the given fraction of requests to the given paths, e.g. 0.01
+// for 1%. Requests are sampled by request id if available, so the start and completion log entries of a request are
+// either both written or both skipped.
+func WithLogCtxSamplePaths(rate float64, paths ...string) LogCtxOption {
+	return func(c *logCtxConfig) {
+		for _, p := range paths {
+			c.samplePaths[p] = rate
+		}
+	}
+}
+
+// WithLogCtxRedactQuery redacts the values of the given query parameters in request logs, e.g. `token` or `api_key`.
+// Parameter names are matched case-insensitively.
+func WithLogCtxRedactQuery(params ...string) LogCtxOption {
+	return func(c *logCtxConfig) {
+		for _, p := range params {
+			c.redactQuery = append(c.redactQuery, strings.ToLower(p))
+		}
+	}
+}
+
+// WithLogCtxRedactPath redacts the path segments matching any of the patterns in request logs, e.g. a pattern matching
+// single-use tokens. Each pattern is matched against whole unescaped segments.
+func WithLogCtxRedactPath(patterns ...*regexp.Regexp) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.redactPath = append(c.redactPath, patterns...)
+	}
+}
+
+// WithLogCtxOmitQuery omits the query from request logs entirely.
+func WithLogCtxOmitQuery() LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.omitQuery = true
+	}
+}
+
+type logCtxConfig struct {
+	fields           []LogCtxField
+	requestHeaders   []string
+	responseHeaders  []string
+	levels           map[int]zapcore.Level
+	skipStart        bool
+	samplePaths      map[string]float64
+	redactQuery      []string
+	redactPath       []*regexp.Regexp
+	omitQuery        bool
+	sampler          *logCtxSampler
+	bodyMaxSize      int
+	bodyContentTypes []string
+	redactKeys       []*regexp.Regexp
+	redactHeaders    []string
+	slow             logCtxSlow
+}
+
+func newLogCtxConfig(opts []LogCtxOption) *logCtxConfig {
+	c := &logCtxConfig{
+		fields:        DefaultLogCtxFields,
+		levels:        map[int]zapcore.Level{},
+		samplePaths:   map[string]float64{},
+		redactKeys:    slices.Clone(DefaultLogCtxRedactKeys),
+		redactHeaders: slices.Clone(DefaultLogCtxRedactHeaders),
+		slow:          logCtxSlow{routes: map[string]time.Duration{}},
+		sampler:       &logCtxSampler{droppedInterval: DefaultLogCtxDroppedInterval, lastReport: time.Now()},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *logCtxConfig) has(field LogCtxField) bool {
+	return slices.Contains(c.fields, field)
+}
+
+// level returns the level of the completion log entry for the status code.
+func (c *logCtxConfig) level(status int) zapcore.Level {
+	if level, ok := c.levels[status/100]; ok {
+		return level
+	}
+	return zap.InfoLevel
+}
+
+// sampled returns whether the log entries of the request are written, and whether the request was dropped by a sample
+// rate rather than skipped.
+func (c *logCtxConfig) sampled(r *http.Request) (sampled bool, dropped bool) {
+	rate, ok := c.samplePaths[r.URL.Path]
+	if !ok {
+		return true, false
+	}
+	sampled = sample(RequestId(r.Context()), rate)
+	return sampled, !sampled && rate > 0
+}
+
+// sample returns whether an entry is sampled at the given rate. Entries are sampled deterministically by key if not
+// empty, so the same decision is made for every entry with the same key.
+func sample(key string, rate float64) bool {
+	switch {
+	case rate <= 0:
+		return false
+	case rate >= 1:
+		return true
+	case key == "":
+		return rand.Float64() < rate
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	// Mix the bits of the hash, as similar keys (e.g. sequential ids) have similar FNV hashes
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x)/float64(math.MaxUint64) < rate
+}
+
+// headerField returns the name of the log field of a header, e.g. `request_header_x_tenant_id`.
+func headerField(prefix, header string) string {
+	return prefix + strings.ReplaceAll(strings.ToLower(header), "-", "_")
+}
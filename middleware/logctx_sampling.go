@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// DefaultLogCtxDroppedInterval is the default minimum interval between log entries reporting the number of requests
+// dropped by sampling. See WithLogCtxDroppedInterval.
+const DefaultLogCtxDroppedInterval = time.Minute
+
+// LogCtxSampleRule is a sampling rule of the logctx middleware, sampling the log entries of requests matching the chi
+// route pattern (e.g. `/search`) and status class (the first digit of the status code, e.g. 2 for 2xx) at the given
+// rate, e.g. 0.01 for 1%. An empty route or zero status class matches any request.
+type LogCtxSampleRule struct {
+	Route       string
+	StatusClass int
+	Rate        float64
+}
+
+func (s LogCtxSampleRule) matches(route string, status int) bool {
+	return (s.Route == "" || s.Route == route) && (s.StatusClass == 0 || s.StatusClass == status/100)
+}
+
+// WithLogCtxSampling samples the log entries of requests by the first matching rule. Requests not matching any rule are
+// always logged, so e.g. a rule for 2xx responses of a route still logs all of its errors. Requests are sampled by
+// request id, so the same decision is made for a request id by every service using the same rules.
+//
+// As the route and status are only known once the request is complete, the start log entry of a request that may be
+// dropped is written with the completion log entry, with the time the request was started, so either both or neither
+// are written. The start log entry of a request kept by every rule (e.g. its request id is sampled by the rate of every
+// rule) is written straight away.
+func WithLogCtxSampling(rules ...LogCtxSampleRule) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.sampler.rules = append(c.sampler.rules, rules...)
+	}
+}
+
+// WithLogCtxSampleSlowerThan always logs requests taking longer than the threshold, regardless of sampling rules.
+func WithLogCtxSampleSlowerThan(threshold time.Duration) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.sampler.slowerThan = threshold
+	}
+}
+
+// WithLogCtxDroppedInterval sets the interval between log entries reporting the number of requests dropped by
+// sampling, defaults to DefaultLogCtxDroppedInterval.
+//
+// Without WithLogCtxDroppedReporter, the number dropped is reported on completion of the first request after the
+// interval has passed, so a server without traffic reports nothing until the next request.
+func WithLogCtxDroppedInterval(interval time.Duration) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.sampler.droppedInterval = interval
+	}
+}
+
+// WithLogCtxDroppedReporter reports the number of requests dropped by sampling every WithLogCtxDroppedInterval from
+// the background goroutine of the reporter, rather than on completion of a later request. A reporter can only be used
+// by one middleware.
+func WithLogCtxDroppedReporter(reporter *LogCtxDroppedReporter) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.sampler.reporter = reporter
+	}
+}
+
+// LogCtxDroppedReporter periodically reports the number of requests dropped by the sampling of a logctx middleware.
+// See WithLogCtxDroppedReporter.
+type LogCtxDroppedReporter struct {
+	mu      sync.Mutex
+	started bool
+	stopped bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewLogCtxDroppedReporter creates a new reporter of requests dropped by sampling. The reporter is started by the
+// middleware using it, and Stop should be called once the server has shut down.
+func NewLogCtxDroppedReporter() *LogCtxDroppedReporter {
+	return &LogCtxDroppedReporter{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// start reports the requests dropped by the sampler every interval, until stopped. It panics if the reporter has
+// already been started by another middleware.
+func (r *LogCtxDroppedReporter) start(s *logCtxSampler, log requestLogger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		panic("middleware: logctx dropped reporter is already used by another middleware")
+	}
+	r.started = true
+	if r.stopped {
+		close(r.done)
+		return
+	}
+
+	interval := s.droppedInterval
+	if interval <= 0 {
+		interval = DefaultLogCtxDroppedInterval
+	}
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.reportAfter(log, 0)
+			case <-r.stop:
+				s.reportAfter(log, 0)
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the reporter, reporting any requests dropped since the last report. It waits for the report to be
+// written, and does nothing if already stopped.
+func (r *LogCtxDroppedReporter) Stop() {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	r.stopped = true
+	started := r.started
+	r.mu.Unlock()
+
+	close(r.stop)
+	if started {
+		<-r.done
+	}
+}
+
+// logCtxSampler samples the log entries of requests by rule, and counts the requests dropped.
+type logCtxSampler struct {
+	rules           []LogCtxSampleRule
+	slowerThan      time.Duration
+	droppedInterval time.Duration
+	reporter        *LogCtxDroppedReporter
+
+	mu         sync.Mutex
+	dropped    int64
+	lastReport time.Time
+}
+
+// enabled returns whether the log entries of requests are sampled by rule.
+func (s *logCtxSampler) enabled() bool {
+	return len(s.rules) > 0
+}
+
+// kept returns whether the log entries of a request are written whatever its route, status and duration, as it is kept
+// by every rule. Requests without a request id are sampled at random, so are only kept by rules with a rate of 1.
+func (s *logCtxSampler) kept(requestId string) bool {
+	for _, rule := range s.rules {
+		if rule.Rate < 1 && (requestId == "" || !sample(requestId, rule.Rate)) {
+			return false
+		}
+	}
+	return true
+}
+
+// keep returns whether the log entries of a completed request are written.
+func (s *logCtxSampler) keep(requestId, route string, status int, duration time.Duration) bool {
+	if s.slowerThan > 0 && duration > s.slowerThan {
+		return true
+	}
+	for _, rule := range s.rules {
+		if rule.matches(route, status) {
+			return sample(requestId, rule.Rate)
+		}
+	}
+	return true
+}
+
+// drop counts a request dropped by sampling.
+func (s *logCtxSampler) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped++
+}
+
+// report logs the number of requests dropped by sampling, if any, once the interval has passed since the last report.
+// Nothing is logged if reported periodically by a reporter instead.
+func (s *logCtxSampler) report(log requestLogger) {
+	if s.reporter == nil {
+		s.reportAfter(log, s.droppedInterval)
+	}
+}
+
+// reportAfter logs the number of requests dropped by sampling, if any, once the interval has passed since the last
+// report.
+func (s *logCtxSampler) reportAfter(log requestLogger, interval time.Duration) {
+	s.mu.Lock()
+	if s.dropped == 0 || time.Since(s.lastReport) < interval {
+		s.mu.Unlock()
+		return
+	}
+	dropped, since := s.dropped, s.lastReport
+	s.dropped, s.lastReport = 0, time.Now()
+	s.mu.Unlock()
+
+	log.Log(
+		context.Background(),
+		time.Time{},
+		zap.InfoLevel,
+		"Request log entries dropped by sampling",
+		zap.Int64("dropped_requests", dropped),
+		zap.Time("since", since),
+	)
+}
@@ -0,0 +1,265 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewLogCtxMiddleware_Sampling(t *testing.T) {
+	rules := WithLogCtxSampling(
+		LogCtxSampleRule{Route: "/search", StatusClass: 2, Rate: 0},
+		LogCtxSampleRule{Route: "/orders/{id}", Rate: 1},
+	)
+	tests := []struct {
+		name         string
+		opts         []LogCtxOption
+		url          string
+		status       int
+		delay        time.Duration
+		wantMessages []string
+	}{
+		{
+			name: "Matching rule drops request",
+			opts: []LogCtxOption{rules},
+			url:  "/search",
+		},
+		{
+			name:         "Errors not matching rule are logged",
+			opts:         []LogCtxOption{rules},
+			url:          "/search",
+			status:       http.StatusInternalServerError,
+			wantMessages: []string{"Request started", "Request complete"},
+		},
+		{
+			name:         "Slow requests are logged",
+			opts:         []LogCtxOption{rules, WithLogCtxSampleSlowerThan(time.Millisecond)},
+			url:          "/search",
+			delay:        5 * time.Millisecond,
+			wantMessages: []string{"Request started", "Request complete"},
+		},
+		{
+			name:         "Rule matches route pattern",
+			opts:         []LogCtxOption{rules},
+			url:          "/orders/123",
+			wantMessages: []string{"Request started", "Request complete"},
+		},
+		{
+			name:         "Start entry is skipped",
+			opts:         []LogCtxOption{rules, WithLogCtxSkipStart()},
+			url:          "/orders/123",
+			wantMessages: []string{"Request complete"},
+		},
+		{
+			name:         "Requests not matching any rule are logged",
+			opts:         []LogCtxOption{WithLogCtxSampling(LogCtxSampleRule{StatusClass: 4, Rate: 0})},
+			url:          "/search",
+			wantMessages: []string{"Request started", "Request complete"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+
+			router := chi.NewRouter()
+			router.Use(NewLogCtxMiddleware(zap.New(core), tt.opts...))
+			handle := func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.delay)
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+			}
+			router.Get("/search", handle)
+			router.Get("/orders/{id}", handle)
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			var messages []string
+			for _, e := range logs.AllUntimed() {
+				messages = append(messages, e.Message)
+			}
+			assert.Equal(t, tt.wantMessages, messages)
+		})
+	}
+}
+
+func TestNewLogCtxMiddleware_SamplingStartEntry(t *testing.T) {
+	// A request id sampled at a rate of 0.5
+	sampledId := "request-0"
+	for i := 1; !sample(sampledId, 0.5); i++ {
+		sampledId = fmt.Sprintf("request-%d", i)
+	}
+
+	tests := []struct {
+		name         string
+		rule         LogCtxSampleRule
+		requestId    string
+		wantDeferred bool
+	}{
+		{
+			name:         "Deferred when the request may be dropped",
+			rule:         LogCtxSampleRule{Route: "/other", Rate: 0},
+			wantDeferred: true,
+		},
+		{
+			name: "Written straight away when kept by every rule",
+			rule: LogCtxSampleRule{StatusClass: 2, Rate: 1},
+		},
+		{
+			name:      "Written straight away when the request id is sampled by every rule",
+			rule:      LogCtxSampleRule{Route: "/search", Rate: 0.5},
+			requestId: sampledId,
+		},
+		{
+			name:         "Deferred without a request id, as sampled at random",
+			rule:         LogCtxSampleRule{Route: "/other", Rate: 0.5},
+			wantDeferred: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+
+			var startedInHandler bool
+			router := chi.NewRouter()
+			if tt.requestId != "" {
+				router.Use(NewRequestIdMiddleware())
+			}
+			router.Use(NewLogCtxMiddleware(zap.New(core), WithLogCtxSampling(tt.rule)))
+			router.Get("/search", func(w http.ResponseWriter, r *http.Request) {
+				startedInHandler = logs.FilterMessage("Request started").Len() > 0
+				time.Sleep(5 * time.Millisecond)
+			})
+			r := httptest.NewRequest(http.MethodGet, "/search?q=abc", nil)
+			if tt.requestId != "" {
+				r.Header.Set(RequestIDHeader, tt.requestId)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, !tt.wantDeferred, startedInHandler)
+			entries := logs.All()
+			if !assert.Len(t, entries, 2) {
+				return
+			}
+			// A deferred start entry is written on completion, with the time the request was started
+			assert.Equal(t, "Request started", entries[0].Message)
+			assert.Equal(t, "/search?q=abc", entries[0].ContextMap()["request_uri"])
+			assert.NotContains(t, entries[0].ContextMap(), "status_code")
+			assert.GreaterOrEqual(t, entries[1].Time.Sub(entries[0].Time), 5*time.Millisecond)
+		})
+	}
+}
+
+func TestNewLogCtxMiddleware_SamplingByRequestId(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	router := chi.NewRouter()
+	router.Use(NewRequestIdMiddleware())
+	router.Use(NewLogCtxMiddleware(zap.New(core), WithLogCtxSampling(LogCtxSampleRule{Route: "/search", Rate: 0.5})))
+	router.Get("/search", func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 200; i++ {
+		for j := 0; j < 2; j++ {
+			// Requests with the same request id are sampled the same
+			req := httptest.NewRequest(http.MethodGet, "/search", nil)
+			req.Header.Set(RequestIDHeader, fmt.Sprintf("request-%d", i))
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+
+	entries := map[any]int{}
+	for _, e := range logs.FilterMessageSnippet("Request ").AllUntimed() {
+		entries[e.ContextMap()["request_id"]]++
+	}
+	for id, n := range entries {
+		assert.Equalf(t, 4, n, "entries of %s", id)
+	}
+	assert.Greater(t, len(entries), 50)
+	assert.Less(t, len(entries), 150)
+}
+
+func TestNewLogCtxMiddleware_SamplingDropped(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	router := chi.NewRouter()
+	router.Use(NewLogCtxMiddleware(
+		zap.New(core),
+		WithLogCtxSampling(LogCtxSampleRule{Route: "/search", Rate: 0}),
+		WithLogCtxDroppedInterval(time.Hour),
+	))
+	router.Get("/search", func(w http.ResponseWriter, r *http.Request) {})
+	for i := 0; i < 3; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/search", nil))
+	}
+	// Not reported until the interval has passed
+	assert.Zero(t, logs.Len())
+
+	// Requests dropped by path sample rate are counted, but skipped paths are not
+	router = chi.NewRouter()
+	router.Use(NewLogCtxMiddleware(
+		zap.New(core),
+		WithLogCtxSampling(LogCtxSampleRule{Route: "/search", Rate: 0}),
+		WithLogCtxSamplePaths(0.000001, "/sampled"),
+		WithLogCtxSkipPaths("/health"),
+		WithLogCtxDroppedInterval(0),
+	))
+	router.Get("/sampled", func(w http.ResponseWriter, r *http.Request) {})
+	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+	for _, path := range []string{"/health", "/sampled", "/health"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	entries := logs.AllUntimed()
+	if !assert.Len(t, entries, 1) {
+		return
+	}
+	assert.Equal(t, "Request log entries dropped by sampling", entries[0].Message)
+	assert.Equal(t, int64(1), entries[0].ContextMap()["dropped_requests"])
+}
+
+func TestNewLogCtxMiddleware_SamplingDroppedReporter(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	dropped := func() []int64 {
+		var n []int64
+		for _, e := range logs.FilterMessage("Request log entries dropped by sampling").AllUntimed() {
+			n = append(n, e.ContextMap()["dropped_requests"].(int64))
+		}
+		return n
+	}
+	newRouter := func(opts ...LogCtxOption) http.Handler {
+		router := chi.NewRouter()
+		router.Use(NewLogCtxMiddleware(
+			zap.New(core),
+			append([]LogCtxOption{WithLogCtxSampling(LogCtxSampleRule{Route: "/search", Rate: 0})}, opts...)...,
+		))
+		router.Get("/search", func(w http.ResponseWriter, r *http.Request) {})
+		return router
+	}
+
+	// Reported once the interval has passed, without a later request
+	reporter := NewLogCtxDroppedReporter()
+	router := newRouter(WithLogCtxDroppedInterval(10*time.Millisecond), WithLogCtxDroppedReporter(reporter))
+	for i := 0; i < 3; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/search", nil))
+	}
+	assert.Eventually(t, func() bool { return len(dropped()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []int64{3}, dropped())
+	reporter.Stop()
+	assert.Panics(t, func() { newRouter(WithLogCtxDroppedReporter(reporter)) }, "reporter is already used")
+
+	// Requests dropped since the last report are reported when stopped
+	logs.TakeAll()
+	reporter = NewLogCtxDroppedReporter()
+	router = newRouter(WithLogCtxDroppedInterval(time.Hour), WithLogCtxDroppedReporter(reporter))
+	for i := 0; i < 2; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/search", nil))
+	}
+	assert.Empty(t, dropped())
+	reporter.Stop()
+	reporter.Stop()
+	assert.Equal(t, []int64{2}, dropped())
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"github.com/ellogroup/ello-golang-ctx/logctx"
+	"go.uber.org/zap"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// maxStackSize is the maximum size of the stacks of all goroutines read to capture the stack of a slow request.
+const maxStackSize = 16 << 20
+
+// WithLogCtxSlowThreshold writes a warning log entry for requests taking longer than the threshold, including the time
+// to first byte and the time spent in the handler versus writing the response body. Slow requests are logged regardless
+// of sampling and skipped paths.
+func WithLogCtxSlowThreshold(threshold time.Duration) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.slow.threshold = threshold
+	}
+}
+
+// WithLogCtxSlowRouteThreshold sets the slow request threshold of a chi route pattern (e.g. `/reports/{id}`),
+// overriding WithLogCtxSlowThreshold. A threshold of 0 disables slow request warnings for the route.
+func WithLogCtxSlowRouteThreshold(route string, threshold time.Duration) LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.slow.routes[route] = threshold
+	}
+}
+
+// WithLogCtxSlowStack includes a snapshot of the stack of the goroutine serving a slow request in the warning log
+// entry, captured when the request is still in flight at the lowest threshold of any route. Capturing the stack briefly
+// stops all goroutines, so this is intended for diagnosing slow requests rather than always being enabled.
+func WithLogCtxSlowStack() LogCtxOption {
+	return func(c *logCtxConfig) {
+		c.slow.stack = true
+	}
+}
+
+type logCtxSlow struct {
+	threshold time.Duration
+	routes    map[string]time.Duration
+	stack     bool
+}
+
+// enabled returns whether slow requests are logged for any route.
+func (s *logCtxSlow) enabled() bool {
+	if s.threshold > 0 {
+		return true
+	}
+	for _, threshold := range s.routes {
+		if threshold > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// thresholdOf returns the slow request threshold of a route, 0 if disabled.
+func (s *logCtxSlow) thresholdOf(route string) time.Duration {
+	if threshold, ok := s.routes[route]; ok {
+		return threshold
+	}
+	return s.threshold
+}
+
+// stackThreshold returns the duration after which the stack of a request in flight is captured, 0 if disabled.
+func (s *logCtxSlow) stackThreshold() time.Duration {
+	if !s.stack {
+		return 0
+	}
+	threshold := s.threshold
+	for _, t := range s.routes {
+		if t > 0 && (threshold <= 0 || t < threshold) {
+			threshold = t
+		}
+	}
+	return threshold
+}
+
+// logSlow writes a warning log entry if the request was slower than the threshold of its route.
+func (c *logCtxConfig) logSlow(
+	log requestLogger,
+	ctx context.Context,
+	route string,
+	status int,
+	duration time.Duration,
+	writes *responseWrites,
+	stack string,
+) {
+	threshold := c.slow.thresholdOf(route)
+	if threshold <= 0 || duration <= threshold {
+		return
+	}
+	fields := []zap.Field{
+		zap.Int("status_code", status),
+		zap.Duration("duration", duration),
+		zap.Duration("slow_threshold", threshold),
+	}
+	if route != "" {
+		fields = append(fields, zap.String(string(LogCtxRoute), route))
+	}
+	if writes != nil && !writes.first.IsZero() {
+		write := writes.last.Sub(writes.first)
+		fields = append(fields,
+			zap.Duration("time_to_first_byte", writes.first.Sub(writes.start)),
+			zap.Duration("handler_duration", duration-write),
+			zap.Duration("write_duration", write),
+		)
+	}
+	if stack != "" {
+		fields = append(fields, zap.String("stack", stack))
+	}
+	log.Log(ctx, time.Time{}, zap.WarnLevel, "Slow request", logctx.Zap(ctx, fields...)...)
+}
+
+// responseWrites records the times of the writes of a response body, as the tee of a response writer, and captures the
+// body if logged.
+type responseWrites struct {
+	start time.Time
+	first time.Time
+	last  time.Time
+	body  *bodyCapture
+}
+
+func (w *responseWrites) Write(p []byte) (int, error) {
+	now := time.Now()
+	if w.first.IsZero() {
+		w.first = now
+	}
+	w.last = now
+	if w.body != nil {
+		_, _ = w.body.Write(p)
+	}
+	return len(p), nil
+}
+
+// stackCapture captures the stack of the current goroutine, if still in flight after a duration.
+type stackCapture struct {
+	timer *time.Timer
+	done  chan struct{}
+	stack string
+}
+
+func captureStackAfter(d time.Duration) *stackCapture {
+	goroutine := currentGoroutine()
+	s := &stackCapture{done: make(chan struct{})}
+	s.timer = time.AfterFunc(d, func() {
+		defer close(s.done)
+		s.stack = goroutineStack(goroutine)
+	})
+	return s
+}
+
+// stop stops the capture, returning the stack if captured.
+func (s *stackCapture) stop() string {
+	if s == nil || s.timer.Stop() {
+		return ""
+	}
+	<-s.done
+	return s.stack
+}
+
+// currentGoroutine returns the header prefix of the stack of the current goroutine, e.g. `goroutine 18 [`.
+func currentGoroutine() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	if i := bytes.IndexByte(buf, '['); i >= 0 {
+		return string(buf[:i+1])
+	}
+	return ""
+}
+
+// goroutineStack returns the stack of the goroutine with the header prefix.
+func goroutineStack(goroutine string) string {
+	if goroutine == "" {
+		return ""
+	}
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxStackSize {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		if strings.HasPrefix(stack, goroutine) {
+			return stack
+		}
+	}
+	return ""
+}
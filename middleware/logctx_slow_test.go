@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bufio"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLogCtxMiddleware_Slow(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []LogCtxOption
+		url      string
+		wantSlow bool
+	}{
+		{
+			name: "Not logged by default",
+			url:  "/reports/1",
+		},
+		{
+			name:     "Slower than threshold",
+			opts:     []LogCtxOption{WithLogCtxSlowThreshold(5 * time.Millisecond)},
+			url:      "/reports/1",
+			wantSlow: true,
+		},
+		{
+			name: "Faster than threshold",
+			opts: []LogCtxOption{WithLogCtxSlowThreshold(time.Minute)},
+			url:  "/reports/1",
+		},
+		{
+			name: "Route threshold overrides threshold",
+			opts: []LogCtxOption{
+				WithLogCtxSlowThreshold(5 * time.Millisecond),
+				WithLogCtxSlowRouteThreshold("/reports/{id}", time.Minute),
+			},
+			url: "/reports/1",
+		},
+		{
+			name: "Route threshold disables warnings",
+			opts: []LogCtxOption{
+				WithLogCtxSlowThreshold(5 * time.Millisecond),
+				WithLogCtxSlowRouteThreshold("/reports/{id}", 0),
+			},
+			url: "/reports/1",
+		},
+		{
+			name:     "Route threshold without threshold",
+			opts:     []LogCtxOption{WithLogCtxSlowRouteThreshold("/reports/{id}", 5*time.Millisecond)},
+			url:      "/reports/1",
+			wantSlow: true,
+		},
+		{
+			name: "Other routes use threshold",
+			opts: []LogCtxOption{WithLogCtxSlowRouteThreshold("/reports/{id}", 5*time.Millisecond)},
+			url:  "/other",
+		},
+		{
+			name:     "Logged for skipped paths",
+			opts:     []LogCtxOption{WithLogCtxSlowThreshold(5 * time.Millisecond), WithLogCtxSkipPaths("/reports/1")},
+			url:      "/reports/1",
+			wantSlow: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+
+			router := chi.NewRouter()
+			router.Use(NewLogCtxMiddleware(zap.New(core), tt.opts...))
+			handle := func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(10 * time.Millisecond)
+				_, _ = w.Write([]byte("a"))
+				time.Sleep(10 * time.Millisecond)
+				_, _ = w.Write([]byte("b"))
+			}
+			router.Get("/reports/{id}", handle)
+			router.Get("/other", handle)
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			slow := logs.FilterMessage("Slow request").All()
+			if !tt.wantSlow {
+				assert.Empty(t, slow)
+				return
+			}
+			if !assert.Len(t, slow, 1) {
+				return
+			}
+			assert.Equal(t, zap.WarnLevel, slow[0].Level)
+			fields := slow[0].ContextMap()
+			assert.Equal(t, "/reports/{id}", fields["route"])
+			assert.Equal(t, tt.url, fields["request_uri"])
+			assert.Equal(t, 5*time.Millisecond, fields["slow_threshold"])
+			assert.GreaterOrEqual(t, fields["time_to_first_byte"], 10*time.Millisecond)
+			assert.GreaterOrEqual(t, fields["write_duration"], 10*time.Millisecond)
+			handler, write := fields["handler_duration"].(time.Duration), fields["write_duration"].(time.Duration)
+			assert.Equal(t, fields["duration"], handler+write)
+			assert.NotContains(t, fields, "stack")
+		})
+	}
+}
+
+func TestNewLogCtxMiddleware_SlowStack(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	router := chi.NewRouter()
+	router.Use(NewLogCtxMiddleware(
+		zap.New(core),
+		WithLogCtxSlowThreshold(time.Minute),
+		WithLogCtxSlowRouteThreshold("/reports/{id}", 5*time.Millisecond),
+		WithLogCtxSlowStack(),
+	))
+	router.Get("/reports/{id}", func(w http.ResponseWriter, r *http.Request) {
+		slowReport()
+	})
+	router.Get("/other", func(w http.ResponseWriter, r *http.Request) {
+		slowReport()
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/reports/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/other", nil))
+
+	slow := logs.FilterMessage("Slow request").All()
+	if !assert.Len(t, slow, 1) {
+		return
+	}
+	fields := slow[0].ContextMap()
+	assert.Equal(t, "/reports/{id}", fields["route"])
+	assert.Contains(t, fields["stack"], "middleware.slowReport")
+	assert.NotContains(t, fields, "time_to_first_byte")
+}
+
+func slowReport() {
+	time.Sleep(20 * time.Millisecond)
+}
+
+// readerFromWriter is a response writer that records whether the body was written with ReadFrom.
+type readerFromWriter struct {
+	*httptest.ResponseRecorder
+	readFrom bool
+}
+
+func (w *readerFromWriter) Flush() {}
+
+func (w *readerFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *readerFromWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.readFrom = true
+	return io.Copy(w.ResponseRecorder, r)
+}
+
+func TestNewLogCtxMiddleware_Tee(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []LogCtxOption
+		wantReadFrom bool
+	}{
+		{
+			name:         "Not installed by default",
+			wantReadFrom: true,
+		},
+		{
+			name: "Installed when logging bodies",
+			opts: []LogCtxOption{WithLogCtxBodies(1024)},
+		},
+		{
+			name: "Installed when logging slow requests",
+			opts: []LogCtxOption{WithLogCtxSlowThreshold(time.Second)},
+		},
+		{
+			name: "Installed when logging slow requests of a route",
+			opts: []LogCtxOption{WithLogCtxSlowRouteThreshold("/reports/{id}", time.Second)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewLogCtxMiddleware(zap.NewNop(), tt.opts...)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					// Hide io.WriterTo of the reader, so the body is written with io.ReaderFrom
+					_, _ = io.Copy(w, struct{ io.Reader }{strings.NewReader("body")})
+				}),
+			)
+			w := &readerFromWriter{ResponseRecorder: httptest.NewRecorder()}
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports/1", nil))
+
+			assert.Equal(t, "body", w.Body.String())
+			assert.Equal(t, tt.wantReadFrom, w.readFrom)
+		})
+	}
+}
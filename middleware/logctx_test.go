@@ -0,0 +1,298 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNewLogCtxMiddleware(t *testing.T) {
+	tests := []struct {
+		name             string
+		opts             []LogCtxOption
+		url              string
+		wantStartFields  map[string]any
+		wantCompleteOnly map[string]any
+		wantMissing      []string
+	}{
+		{
+			name: "Path, query and route are logged",
+			url:  "/orders/123?token=abc&page=2",
+			wantStartFields: map[string]any{
+				"http_method": "GET",
+				"request_uri": "/orders/123?token=abc&page=2",
+				"path":        "/orders/123",
+				"query":       "token=abc&page=2",
+			},
+			wantCompleteOnly: map[string]any{"route": "/orders/{id}", "status_code": int64(http.StatusOK)},
+		},
+		{
+			name: "Query parameters are redacted",
+			opts: []LogCtxOption{WithLogCtxRedactQuery("Token", "api_key")},
+			url:  "/orders/123?token=abc&page=2&API_KEY=def&api_key",
+			wantStartFields: map[string]any{
+				"request_uri": "/orders/123?token=REDACTED&page=2&API_KEY=REDACTED&api_key",
+				"path":        "/orders/123",
+				"query":       "token=REDACTED&page=2&API_KEY=REDACTED&api_key",
+			},
+		},
+		{
+			name: "Path segments are redacted",
+			opts: []LogCtxOption{WithLogCtxRedactPath(regexp.MustCompile(`^tok_`), regexp.MustCompile(`@`))},
+			url:  "/reset/tok_abc/users/a%40b.com?page=2",
+			wantStartFields: map[string]any{
+				"request_uri": "/reset/REDACTED/users/REDACTED?page=2",
+				"path":        "/reset/REDACTED/users/REDACTED",
+			},
+			wantCompleteOnly: map[string]any{"route": "/reset/{token}/users/{email}"},
+		},
+		{
+			name: "Query is omitted",
+			opts: []LogCtxOption{WithLogCtxOmitQuery()},
+			url:  "/orders/123?token=abc",
+			wantStartFields: map[string]any{
+				"request_uri": "/orders/123",
+				"path":        "/orders/123",
+			},
+			wantMissing: []string{"query"},
+		},
+		{
+			name:            "Unrouted request has no route",
+			url:             "/missing",
+			wantStartFields: map[string]any{"path": "/missing"},
+			wantMissing:     []string{"query", "route"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+
+			router := chi.NewRouter()
+			router.Use(NewLogCtxMiddleware(zap.New(core), tt.opts...))
+			router.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			})
+			router.Get("/reset/{token}/users/{email}", func(w http.ResponseWriter, r *http.Request) {})
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			entries := logs.AllUntimed()
+			if !assert.Len(t, entries, 2) {
+				return
+			}
+			start, complete := entries[0].ContextMap(), entries[1].ContextMap()
+			assert.Equal(t, "Request started", entries[0].Message)
+			assert.Equal(t, "Request complete", entries[1].Message)
+			for k, v := range tt.wantStartFields {
+				assert.Equalf(t, v, start[k], "start field %s", k)
+				assert.Equalf(t, v, complete[k], "complete field %s", k)
+			}
+			for k, v := range tt.wantCompleteOnly {
+				assert.NotContainsf(t, start, k, "start field %s", k)
+				assert.Equalf(t, v, complete[k], "complete field %s", k)
+			}
+			for _, k := range tt.wantMissing {
+				assert.NotContainsf(t, complete, k, "complete field %s", k)
+			}
+		})
+	}
+}
+
+func TestNewLogCtxMiddleware_Options(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []LogCtxOption
+		url           string
+		status        int
+		wantMessages  []string
+		wantLevel     zapcore.Level
+		wantFields    map[string]any
+		wantMissing   []string
+		wantCompleted map[string]any
+	}{
+		{
+			name: "Custom fields and headers",
+			opts: []LogCtxOption{
+				WithLogCtxFields(LogCtxMethod, LogCtxHost, LogCtxScheme, LogCtxContentLength),
+				WithLogCtxRequestHeaders("X-Tenant-Id", "X-Missing"),
+				WithLogCtxResponseHeaders("Cache-Control"),
+			},
+			url:          "/orders/123",
+			wantMessages: []string{"Request started", "Request complete"},
+			wantLevel:    zap.InfoLevel,
+			wantFields: map[string]any{
+				"http_method":                "GET",
+				"host":                       "example.com",
+				"scheme":                     "http",
+				"content_length":             "0",
+				"request_header_x_tenant_id": "tenant",
+			},
+			wantMissing:   []string{"request_uri", "path", "route", "request_header_x_missing"},
+			wantCompleted: map[string]any{"response_header_cache_control": "no-store"},
+		},
+		{
+			name:         "Level by status class",
+			opts:         []LogCtxOption{WithLogCtxLevel(5, zap.ErrorLevel), WithLogCtxLevel(4, zap.WarnLevel)},
+			url:          "/orders/123",
+			status:       http.StatusServiceUnavailable,
+			wantMessages: []string{"Request started", "Request complete"},
+			wantLevel:    zap.ErrorLevel,
+		},
+		{
+			name:         "Level of other status classes is unchanged",
+			opts:         []LogCtxOption{WithLogCtxLevel(5, zap.ErrorLevel)},
+			url:          "/orders/123",
+			status:       http.StatusNotFound,
+			wantMessages: []string{"Request started", "Request complete"},
+			wantLevel:    zap.InfoLevel,
+		},
+		{
+			name:         "Start is skipped",
+			opts:         []LogCtxOption{WithLogCtxSkipStart()},
+			url:          "/orders/123",
+			wantMessages: []string{"Request complete"},
+			wantLevel:    zap.InfoLevel,
+		},
+		{
+			name: "Path is skipped",
+			opts: []LogCtxOption{WithLogCtxSkipPaths("/health")},
+			url:  "/health",
+		},
+		{
+			name:         "Other paths are not skipped",
+			opts:         []LogCtxOption{WithLogCtxSkipPaths("/health")},
+			url:          "/orders/123",
+			wantMessages: []string{"Request started", "Request complete"},
+			wantLevel:    zap.InfoLevel,
+		},
+		{
+			name:          "Empty response is logged as 200",
+			url:           "/empty",
+			wantMessages:  []string{"Request started", "Request complete"},
+			wantLevel:     zap.InfoLevel,
+			wantCompleted: map[string]any{"status_code": int64(http.StatusOK)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+
+			router := chi.NewRouter()
+			router.Use(NewLogCtxMiddleware(zap.New(core), tt.opts...))
+			router.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", "no-store")
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = w.Write([]byte("ok"))
+			})
+			router.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+			router.Get("/empty", func(w http.ResponseWriter, r *http.Request) {})
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req.Header.Set("X-Tenant-Id", "tenant")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			entries := logs.AllUntimed()
+			var messages []string
+			for _, e := range entries {
+				messages = append(messages, e.Message)
+			}
+			if !assert.Equal(t, tt.wantMessages, messages) || len(entries) == 0 {
+				return
+			}
+			complete := entries[len(entries)-1]
+			assert.Equal(t, tt.wantLevel, complete.Level)
+			for _, e := range entries {
+				fields := e.ContextMap()
+				for k, v := range tt.wantFields {
+					assert.Equalf(t, v, fields[k], "field %s", k)
+				}
+				for _, k := range tt.wantMissing {
+					assert.NotContainsf(t, fields, k, "field %s", k)
+				}
+			}
+			for k, v := range tt.wantCompleted {
+				assert.Equalf(t, v, complete.ContextMap()[k], "complete field %s", k)
+			}
+		})
+	}
+}
+
+func TestNewLogCtxMiddleware_SamplePaths(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	router := chi.NewRouter()
+	router.Use(NewRequestIdMiddleware())
+	router.Use(NewLogCtxMiddleware(zap.New(core), WithLogCtxSamplePaths(0.5, "/search")))
+	router.Get("/search", func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 200; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		req.Header.Set(RequestIDHeader, fmt.Sprintf("request-%d", i))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Start and completion entries are sampled together, by request id
+	started := map[any]int{}
+	for _, e := range logs.AllUntimed() {
+		started[e.ContextMap()["request_id"]]++
+	}
+	for id, n := range started {
+		assert.Equalf(t, 2, n, "entries of %s", id)
+	}
+	assert.Greater(t, len(started), 50)
+	assert.Less(t, len(started), 150)
+}
+
+func TestSample(t *testing.T) {
+	assert.False(t, sample("key", 0))
+	assert.True(t, sample("key", 1))
+	assert.Equal(t, sample("key", 0.5), sample("key", 0.5))
+
+	sampled := 0
+	for i := 0; i < 10_000; i++ {
+		if sample(fmt.Sprintf("key-%d", i), 0.1) {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 1_000, sampled, 150)
+}
+
+func TestNewSlogLogCtxMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	router := chi.NewRouter()
+	router.Use(NewSlogLogCtxMiddleware(log, WithLogCtxLevel(5, zap.ErrorLevel), WithLogCtxSlowThreshold(time.Nanosecond)))
+	router.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/123?page=2", nil))
+
+	entries := decodeSlogEntries(t, &buf)
+	if !assert.Len(t, entries, 3) {
+		return
+	}
+	assert.Equal(t, "Request started", entries[0]["msg"])
+	assert.Equal(t, "INFO", entries[0]["level"])
+	assert.Equal(t, "Slow request", entries[1]["msg"])
+	assert.Equal(t, "WARN", entries[1]["level"])
+	assert.Equal(t, "Request complete", entries[2]["msg"])
+	assert.Equal(t, "ERROR", entries[2]["level"])
+	for _, entry := range entries {
+		assert.Equal(t, "/orders/123", entry["path"])
+		assert.Equal(t, "page=2", entry["query"])
+	}
+	assert.Equal(t, float64(http.StatusInternalServerError), entries[2]["status_code"])
+	assert.Equal(t, "/orders/{id}", entries[2]["route"])
+	assert.Contains(t, entries[2], "duration")
+}
@@ -3,8 +3,11 @@ package middleware
 import (
 	"context"
 	"errors"
+	"github.com/ellogroup/ello-golang-ctx/logctx"
 	chi_middleware "github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -80,9 +83,55 @@ func LoggerOrError(ctx context.Context) (*zap.Logger, error) {
 
 // Logger will extract the logger from the request context. If the logger is not set in the context a noop logger will
 // be returned.
+//
+// Deprecated: The logger is only set by NewZapLoggerMiddleware, so this returns a noop logger when using
+// NewLogCtxMiddleware. Use Log instead, which logs through whichever logger is configured.
 func Logger(ctx context.Context) *zap.Logger {
 	if log, err := LoggerOrError(ctx); err == nil {
 		return log
 	}
 	return zap.NewNop()
 }
+
+// LogFunc writes a log entry for a request, such as Log.
+type LogFunc func(ctx context.Context, level zapcore.Level, msg string, fields ...zap.Field)
+
+// Log writes a log entry through the logger of the request context, whichever is configured: the zap logger added by
+// NewZapLoggerMiddleware, the slog logger added by NewSlogLoggerMiddleware, or the logger of NewLogCtxMiddleware or
+// NewSlogLogCtxMiddleware, with the logctx fields of the request. Fields are converted to attributes for slog. If no
+// logger is set in the context, the log entry is discarded.
+func Log(ctx context.Context, level zapcore.Level, msg string, fields ...zap.Field) {
+	if ctx == nil {
+		return
+	}
+	if log, err := LoggerOrError(ctx); err == nil {
+		zapRequestLogger{log}.Log(ctx, time.Time{}, level, msg, fields...)
+		return
+	}
+	if log, err := SlogLoggerOrError(ctx); err == nil {
+		slogRequestLogger{log}.Log(ctx, time.Time{}, level, msg, fields...)
+		return
+	}
+	if req, ok := ctx.Value(logCtxRequestKey{}).(*logCtxRequest); ok && req.log != nil {
+		logCtxLogFunc(req.log)(ctx, level, msg, fields...)
+	}
+}
+
+// ZapLogFunc returns a LogFunc writing log entries to a zap logger, with the logctx fields of the request.
+func ZapLogFunc(log *zap.Logger) LogFunc {
+	return logCtxLogFunc(zapRequestLogger{log})
+}
+
+// SlogLogFunc returns a LogFunc writing log entries to a slog logger, with the logctx fields of the request.
+func SlogLogFunc(log *slog.Logger) LogFunc {
+	return logCtxLogFunc(slogRequestLogger{log})
+}
+
+func logCtxLogFunc(log requestLogger) LogFunc {
+	return func(ctx context.Context, level zapcore.Level, msg string, fields ...zap.Field) {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		log.Log(ctx, time.Time{}, level, msg, logctx.Zap(ctx, fields...)...)
+	}
+}
@@ -1,11 +1,18 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"github.com/ellogroup/ello-golang-ctx/logctx"
 	"github.com/ellogroup/ello-golang-http/internal/mock"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -81,3 +88,132 @@ func TestLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      func(zapLog *zap.Logger, slogLog *slog.Logger) context.Context
+		wantZap  int
+		wantSlog int
+	}{
+		{
+			name: "Zap logger in context",
+			ctx: func(zapLog *zap.Logger, _ *slog.Logger) context.Context {
+				return context.WithValue(context.Background(), LoggerCtxKey, zapLog)
+			},
+			wantZap: 1,
+		},
+		{
+			name: "Slog logger in context",
+			ctx: func(_ *zap.Logger, slogLog *slog.Logger) context.Context {
+				return context.WithValue(context.Background(), SlogLoggerCtxKey, slogLog)
+			},
+			wantSlog: 1,
+		},
+		{
+			name: "No logger in context",
+			ctx: func(*zap.Logger, *slog.Logger) context.Context {
+				return context.Background()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			var buf bytes.Buffer
+			slogLog := slog.New(slog.NewJSONHandler(&buf, nil))
+
+			ctx := tt.ctx(zap.New(core), slogLog)
+			Log(ctx, zap.ErrorLevel, "Unable to write response", zap.Error(errors.New("failed")))
+
+			assert.Equal(t, tt.wantZap, logs.FilterMessage("Unable to write response").Len())
+			entries := decodeSlogEntries(t, &buf)
+			if assert.Len(t, entries, tt.wantSlog) && tt.wantSlog > 0 {
+				assert.Equal(t, "ERROR", entries[0]["level"])
+				assert.Equal(t, "failed", entries[0]["error"])
+			}
+		})
+	}
+}
+
+func TestLog_LogCtx(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware func(zapLog *zap.Logger, slogLog *slog.Logger) func(http.Handler) http.Handler
+		wantZap    int
+		wantSlog   int
+	}{
+		{
+			name: "Logctx middleware",
+			middleware: func(zapLog *zap.Logger, _ *slog.Logger) func(http.Handler) http.Handler {
+				return NewLogCtxMiddleware(zapLog, WithLogCtxSkipStart())
+			},
+			wantZap: 1,
+		},
+		{
+			name: "Slog logctx middleware",
+			middleware: func(_ *zap.Logger, slogLog *slog.Logger) func(http.Handler) http.Handler {
+				return NewSlogLogCtxMiddleware(slogLog, WithLogCtxSkipStart())
+			},
+			wantSlog: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			var buf bytes.Buffer
+			slogLog := slog.New(slog.NewJSONHandler(&buf, nil))
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Log(r.Context(), zap.ErrorLevel, "Unable to write response", zap.Error(errors.New("failed")))
+			})
+			handler := tt.middleware(zap.New(core), slogLog)(next)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+			zapEntries := logs.FilterMessage("Unable to write response").All()
+			if assert.Len(t, zapEntries, tt.wantZap) && tt.wantZap > 0 {
+				assert.Equal(t, "/orders", zapEntries[0].ContextMap()["path"])
+				assert.Equal(t, "failed", zapEntries[0].ContextMap()["error"])
+			}
+			var slogEntries []map[string]any
+			for _, entry := range decodeSlogEntries(t, &buf) {
+				if entry["msg"] == "Unable to write response" {
+					slogEntries = append(slogEntries, entry)
+				}
+			}
+			if assert.Len(t, slogEntries, tt.wantSlog) && tt.wantSlog > 0 {
+				assert.Equal(t, "/orders", slogEntries[0]["path"])
+				assert.Equal(t, "failed", slogEntries[0]["error"])
+			}
+		})
+	}
+}
+
+func TestZapLogFunc(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	ctx := logctx.Add(context.Background(), logctx.String("path", "/orders"))
+
+	ZapLogFunc(zap.New(core))(ctx, zap.WarnLevel, "Test", zap.String("a", "b"))
+	ZapLogFunc(zap.New(core))(ctx, zap.DebugLevel, "Not enabled")
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, zap.WarnLevel, entries[0].Level)
+		assert.Equal(t, map[string]any{"path": "/orders", "a": "b"}, entries[0].ContextMap())
+	}
+}
+
+func TestSlogLogFunc(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logctx.Add(context.Background(), logctx.String("path", "/orders"))
+
+	SlogLogFunc(slog.New(slog.NewJSONHandler(&buf, nil)))(ctx, zap.WarnLevel, "Test", zap.Int("a", 1))
+	SlogLogFunc(slog.New(slog.NewJSONHandler(&buf, nil)))(ctx, zap.DebugLevel, "Not enabled")
+
+	entries := decodeSlogEntries(t, &buf)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "WARN", entries[0]["level"])
+		assert.Equal(t, "/orders", entries[0]["path"])
+		assert.Equal(t, float64(1), entries[0]["a"])
+	}
+}
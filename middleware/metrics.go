@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	chi_middleware "github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the default buckets of the request duration histogram, in seconds.
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the default buckets of the request and response size histograms, in bytes.
+var DefaultSizeBuckets = []float64{100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+// metricsMethods are the methods used as label values. Any other method is labelled as OTHER, so that arbitrary methods
+// can not be used to create new series.
+var metricsMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// MetricsOption configures the HTTP metrics.
+type MetricsOption func(*Metrics)
+
+// WithMetricsNamespace sets the prefix of the metric names, e.g. `myapp` for `myapp_http_requests_total`.
+func WithMetricsNamespace(namespace string) MetricsOption {
+	return func(m *Metrics) {
+		m.namespace = namespace
+	}
+}
+
+// WithMetricsDurationBuckets sets the upper bounds of the buckets of the request duration histogram, in seconds.
+func WithMetricsDurationBuckets(buckets ...float64) MetricsOption {
+	return func(m *Metrics) {
+		m.durationBuckets = sortedBuckets(buckets)
+	}
+}
+
+// WithMetricsSizeBuckets sets the upper bounds of the buckets of the request and response size histograms, in bytes.
+func WithMetricsSizeBuckets(buckets ...float64) MetricsOption {
+	return func(m *Metrics) {
+		m.sizeBuckets = sortedBuckets(buckets)
+	}
+}
+
+// Metrics holds the HTTP metrics recorded by the metrics middleware, and is a http.Handler exposing them in the
+// Prometheus text exposition format.
+type Metrics struct {
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+
+	mu            sync.Mutex
+	requests      map[metricsLabels]uint64
+	durations     map[metricsLabels]*histogram
+	requestSizes  map[metricsLabels]*histogram
+	responseSizes map[metricsLabels]*histogram
+	inFlight      map[string]int64
+}
+
+type metricsLabels struct {
+	method string
+	route  string
+	status string
+}
+
+// NewMetrics returns new HTTP metrics, to be recorded with NewMetricsMiddleware.
+func NewMetrics(opts ...MetricsOption) *Metrics {
+	m := &Metrics{
+		durationBuckets: DefaultDurationBuckets,
+		sizeBuckets:     DefaultSizeBuckets,
+		requests:        map[metricsLabels]uint64{},
+		durations:       map[metricsLabels]*histogram{},
+		requestSizes:    map[metricsLabels]*histogram{},
+		responseSizes:   map[metricsLabels]*histogram{},
+		inFlight:        map[string]int64{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// NewMetricsMiddleware returns a handler to be used as middleware. This middleware will record the count, duration and
+// request and response sizes of requests, and the number of requests in flight, to the metrics. Requests are labelled
+// by method, chi route pattern (e.g. `/users/{id}`) and status class (e.g. `2xx`), so the number of series does not
+// grow with the number of request paths. Requests that are not routed have a route of `unmatched`.
+//
+// Panics are recorded with a status of 500, as the response a recoverer middleware writes, before being re-raised, so
+// it is recommended this comes _before_ any recoverer middleware.
+func NewMetricsMiddleware(metrics *Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := metricsMethod(r.Method)
+			metrics.addInFlight(method, 1)
+
+			// Count the bytes of the request body read by the handler, for bodies without a Content-Length
+			body := &countingReader{ReadCloser: r.Body}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = body
+			}
+
+			// Wrap the response writer, so we can access details of the response, such as status code
+			ww := chi_middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			requestStart := time.Now()
+			defer func() {
+				route := "unmatched"
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					route = rctx.RoutePattern()
+				}
+				status := responseStatus(ww)
+				rec := recover()
+				if rec != nil {
+					status = http.StatusInternalServerError
+				}
+				labels := metricsLabels{
+					method: method,
+					route:  route,
+					status: fmt.Sprintf("%dxx", status/100),
+				}
+				// The handler may not read the whole body
+				metrics.observe(labels, time.Since(requestStart), max(body.n, r.ContentLength), ww.BytesWritten())
+				metrics.addInFlight(method, -1)
+				if rec != nil {
+					panic(rec)
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+func (m *Metrics) addInFlight(method string, n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight[method] += n
+}
+
+func (m *Metrics) observe(labels metricsLabels, duration time.Duration, requestSize int64, responseSize int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[labels]++
+	observeHistogram(m.durations, labels, m.durationBuckets, duration.Seconds())
+	observeHistogram(m.requestSizes, labels, m.sizeBuckets, float64(requestSize))
+	observeHistogram(m.responseSizes, labels, m.sizeBuckets, float64(responseSize))
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := m.WriteTo(w); err != nil {
+		// Unable to write the response to the response writer
+		Log(r.Context(), zap.ErrorLevel, "Unable to write response", zap.Error(err))
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	name := m.name("http_requests_total")
+	writeMetricHeader(&b, name, "counter", "Total number of HTTP requests.")
+	for _, labels := range sortedLabels(m.requests) {
+		fmt.Fprintf(&b, "%s%s %d\n", name, labels.format(""), m.requests[labels])
+	}
+
+	name = m.name("http_requests_in_flight")
+	writeMetricHeader(&b, name, "gauge", "Number of HTTP requests currently being served.")
+	methods := make([]string, 0, len(m.inFlight))
+	for method := range m.inFlight {
+		methods = append(methods, method)
+	}
+	slices.Sort(methods)
+	for _, method := range methods {
+		fmt.Fprintf(&b, "%s{method=\"%s\"} %d\n", name, escapeLabelValue(method), m.inFlight[method])
+	}
+
+	writeHistograms(&b, m.name("http_request_duration_seconds"), "Duration of HTTP requests in seconds.", m.durations)
+	writeHistograms(&b, m.name("http_request_size_bytes"), "Size of HTTP request bodies in bytes.", m.requestSizes)
+	writeHistograms(&b, m.name("http_response_size_bytes"), "Size of HTTP response bodies in bytes.", m.responseSizes)
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (m *Metrics) name(name string) string {
+	if m.namespace == "" {
+		return name
+	}
+	return m.namespace + "_" + name
+}
+
+// format returns the labels in the exposition format, with an optional `le` label for histogram buckets.
+func (l metricsLabels) format(le string) string {
+	s := fmt.Sprintf(`{method="%s",route="%s",status="%s"`,
+		escapeLabelValue(l.method), escapeLabelValue(l.route), escapeLabelValue(l.status))
+	if le != "" {
+		s += fmt.Sprintf(`,le="%s"`, le)
+	}
+	return s + "}"
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func observeHistogram(hs map[metricsLabels]*histogram, labels metricsLabels, buckets []float64, v float64) {
+	h, ok := hs[labels]
+	if !ok {
+		h = &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		hs[labels] = h
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func writeHistograms(b *strings.Builder, name, help string, hs map[metricsLabels]*histogram) {
+	writeMetricHeader(b, name, "histogram", help)
+	for _, labels := range sortedLabels(hs) {
+		h := hs[labels]
+		for i, upper := range h.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", name, labels.format(formatFloat(upper)), h.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", name, labels.format("+Inf"), h.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", name, labels.format(""), formatFloat(h.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", name, labels.format(""), h.count)
+	}
+}
+
+func writeMetricHeader(b *strings.Builder, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func sortedLabels[V any](series map[metricsLabels]V) []metricsLabels {
+	labels := make([]metricsLabels, 0, len(series))
+	for l := range series {
+		labels = append(labels, l)
+	}
+	slices.SortFunc(labels, func(a, b metricsLabels) int {
+		return strings.Compare(a.method+"\x00"+a.route+"\x00"+a.status, b.method+"\x00"+b.route+"\x00"+b.status)
+	})
+	return labels
+}
+
+func sortedBuckets(buckets []float64) []float64 {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return slices.Compact(buckets)
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func metricsMethod(method string) string {
+	if slices.Contains(metricsMethods, method) {
+		return method
+	}
+	return "OTHER"
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
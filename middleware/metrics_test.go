@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"github.com/go-chi/chi/v5"
+	chi_middleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewMetricsMiddleware(t *testing.T) {
+	metrics := NewMetrics(
+		WithMetricsNamespace("test"),
+		WithMetricsDurationBuckets(60, 30),
+		WithMetricsSizeBuckets(10, 100, 10),
+	)
+
+	var inFlight string
+	router := chi.NewRouter()
+	router.Use(NewMetricsMiddleware(metrics))
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("user"))
+	})
+	router.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(strings.Repeat("a", 50)))
+	})
+	router.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		metrics.ServeHTTP(rec, r)
+		inFlight = rec.Body.String()
+	})
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/users/2", nil),
+		httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"test"}`)),
+		httptest.NewRequest(http.MethodGet, "/missing", nil),
+		httptest.NewRequest("PURGE", "/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/metrics", nil),
+	}
+	// Body without a Content-Length is counted as it is read
+	requests[2].ContentLength = -1
+	for _, r := range requests {
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	assert.Contains(t, inFlight, "test_http_requests_in_flight{method=\"GET\"} 1\n")
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# HELP test_http_requests_total Total number of HTTP requests.\n# TYPE test_http_requests_total counter\n",
+		`test_http_requests_total{method="GET",route="/metrics",status="2xx"} 1` + "\n",
+		`test_http_requests_total{method="GET",route="/users/{id}",status="2xx"} 2` + "\n",
+		`test_http_requests_total{method="GET",route="unmatched",status="4xx"} 1` + "\n",
+		`test_http_requests_total{method="OTHER",route="unmatched",status="4xx"} 1` + "\n",
+		`test_http_requests_total{method="POST",route="/users",status="2xx"} 1` + "\n",
+		"# TYPE test_http_requests_in_flight gauge\n",
+		`test_http_requests_in_flight{method="GET"} 0` + "\n",
+		"# TYPE test_http_request_duration_seconds histogram\n",
+		`test_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="2xx",le="30"} 2` + "\n",
+		`test_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="2xx",le="60"} 2` + "\n",
+		`test_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="2xx",le="+Inf"} 2` + "\n",
+		`test_http_request_duration_seconds_count{method="GET",route="/users/{id}",status="2xx"} 2` + "\n",
+		`test_http_request_size_bytes_bucket{method="POST",route="/users",status="2xx",le="10"} 0` + "\n",
+		`test_http_request_size_bytes_bucket{method="POST",route="/users",status="2xx",le="100"} 1` + "\n",
+		`test_http_request_size_bytes_sum{method="POST",route="/users",status="2xx"} 15` + "\n",
+		`test_http_response_size_bytes_bucket{method="GET",route="/users/{id}",status="2xx",le="10"} 2` + "\n",
+		`test_http_response_size_bytes_sum{method="GET",route="/users/{id}",status="2xx"} 8` + "\n",
+		`test_http_response_size_bytes_bucket{method="POST",route="/users",status="2xx",le="10"} 0` + "\n",
+		`test_http_response_size_bytes_bucket{method="POST",route="/users",status="2xx",le="100"} 1` + "\n",
+		`test_http_response_size_bytes_bucket{method="POST",route="/users",status="2xx",le="+Inf"} 1` + "\n",
+		`test_http_response_size_bytes_sum{method="POST",route="/users",status="2xx"} 50` + "\n",
+	} {
+		assert.Contains(t, body, want)
+	}
+	assert.NotContains(t, body, "/users/1")
+	assert.NotContains(t, body, "PURGE")
+}
+
+func TestNewMetricsMiddleware_Panic(t *testing.T) {
+	metrics := NewMetrics(WithMetricsNamespace("test"))
+	router := chi.NewRouter()
+	router.Use(chi_middleware.Recoverer, NewMetricsMiddleware(metrics))
+	router.Get("/panic", func(http.ResponseWriter, *http.Request) {
+		panic("handler failed")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `test_http_requests_total{method="GET",route="/panic",status="5xx"} 1`+"\n")
+	assert.Contains(t, rec.Body.String(), `test_http_requests_in_flight{method="GET"} 0`+"\n")
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	assert.Equal(t, `a\\b\"c\nd`, escapeLabelValue("a\\b\"c\nd"))
+}
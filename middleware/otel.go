@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/go-chi/chi/v5"
+	chi_middleware "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+const otelTracerName = "github.com/ellogroup/ello-golang-http/middleware"
+
+type otelRequestKey struct{}
+
+// otelRequest holds the state of a request being processed by the OpenTelemetry middleware.
+type otelRequest struct {
+	errorRecorded atomic.Bool
+}
+
+// OtelOption configures the OpenTelemetry middleware.
+type OtelOption func(*otelConfig)
+
+// WithOtelTracerProvider sets the tracer provider used to create spans. By default, the global tracer provider is used.
+func WithOtelTracerProvider(provider trace.TracerProvider) OtelOption {
+	return func(c *otelConfig) {
+		c.provider = provider
+	}
+}
+
+// WithOtelPropagator sets the propagator used to extract the trace context from the request headers. By default, the
+// global propagator is used.
+func WithOtelPropagator(propagator propagation.TextMapPropagator) OtelOption {
+	return func(c *otelConfig) {
+		c.propagator = propagator
+	}
+}
+
+type otelConfig struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// NewOtelMiddleware returns a handler to be used as middleware. This middleware will start an OpenTelemetry server span
+// for each request, continuing the trace from the request headers, and add it to the request context. The span is named
+// and attributed following the HTTP semantic conventions, using the chi route pattern rather than the request path.
+//
+// The span status is set to error for 5xx responses and panics. Panics are recorded on the span as an exception event
+// before being re-raised, so it is recommended this comes _before_ any recoverer middleware. Error responses written by
+// handler.New are recorded as span events using RecordErrorResponse.
+func NewOtelMiddleware(opts ...OtelOption) func(http.Handler) http.Handler {
+	c := &otelConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provider, propagator := c.provider, c.propagator
+			if provider == nil {
+				provider = otel.GetTracerProvider()
+			}
+			if propagator == nil {
+				propagator = otel.GetTextMapPropagator()
+			}
+
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := provider.Tracer(otelTracerName).Start(
+				ctx,
+				r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(otelRequestAttributes(r)...),
+			)
+
+			req := &otelRequest{}
+			ctx = context.WithValue(ctx, otelRequestKey{}, req)
+
+			// Wrap the response writer, so we can access details of the response, such as status code
+			ww := chi_middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				// The route pattern is only known once the request has been routed
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					span.SetName(r.Method + " " + rctx.RoutePattern())
+					span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+				}
+
+				if rec := recover(); rec != nil {
+					span.RecordError(fmt.Errorf("panic: %v", rec), trace.WithStackTrace(true))
+					span.SetStatus(codes.Error, "panic")
+					// Not deferred, so the span does not also record the panic
+					span.End()
+					panic(rec)
+				}
+
+				status := responseStatus(ww)
+				span.SetAttributes(
+					semconv.HTTPResponseStatusCode(status),
+					semconv.HTTPResponseBodySize(ww.BytesWritten()),
+				)
+				if status >= 500 && !req.errorRecorded.Load() {
+					span.SetStatus(codes.Error, "")
+				}
+				span.End()
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+// responseStatus returns the status code written to the response, or 200 OK if nothing was written, as that is what
+// the server will respond with.
+func responseStatus(ww chi_middleware.WrapResponseWriter) int {
+	if status := ww.Status(); status != 0 {
+		return status
+	}
+	return http.StatusOK
+}
+
+// RecordErrorResponse records an error response on the span of the request as an `error_response` event. For 5xx
+// errors, the span status is also set to error, with the message of the error as the description.
+func RecordErrorResponse(ctx context.Context, e response.ErrorDetails) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.AddEvent("error_response", trace.WithAttributes(
+		semconv.HTTPResponseStatusCode(e.Status),
+		attribute.String("error.code", e.Code),
+		attribute.String("error.message", e.Message),
+	))
+	if e.Status >= 500 {
+		span.SetStatus(codes.Error, e.Message)
+		if req, ok := ctx.Value(otelRequestKey{}).(*otelRequest); ok {
+			req.errorRecorded.Store(true)
+		}
+	}
+}
+
+func otelRequestAttributes(r *http.Request) []attribute.KeyValue {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLPath(r.URL.Path),
+		semconv.URLScheme(scheme),
+		semconv.NetworkProtocolVersion(fmt.Sprintf("%d.%d", r.ProtoMajor, r.ProtoMinor)),
+	}
+	if host, port, err := net.SplitHostPort(r.Host); err == nil {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if p, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, semconv.ServerPort(p))
+		}
+	} else if r.Host != "" {
+		attrs = append(attrs, semconv.ServerAddress(r.Host))
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		attrs = append(attrs, semconv.ClientAddress(host))
+	}
+	if ua := r.UserAgent(); ua != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(ua))
+	}
+	if r.ContentLength > 0 {
+		attrs = append(attrs, semconv.HTTPRequestBodySize(int(r.ContentLength)))
+	}
+	return attrs
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewOtelMiddleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		url             string
+		header          http.Header
+		body            string
+		wantName        string
+		wantAttributes  map[attribute.Key]attribute.Value
+		wantStatus      codes.Code
+		wantDescription string
+		wantEvents      []string
+		wantPanic       bool
+		wantTraceId     string
+		wantParentId    string
+	}{
+		{
+			name:     "Successful request",
+			method:   http.MethodPost,
+			url:      "https://example.com:8443/users/1?token=secret",
+			header:   http.Header{"User-Agent": {"test"}},
+			body:     `{"name":"test"}`,
+			wantName: "POST /users/{id}",
+			wantAttributes: map[attribute.Key]attribute.Value{
+				"http.request.method":       attribute.StringValue("POST"),
+				"http.route":                attribute.StringValue("/users/{id}"),
+				"http.response.status_code": attribute.IntValue(http.StatusCreated),
+				"http.request.body.size":    attribute.IntValue(15),
+				"http.response.body.size":   attribute.IntValue(7),
+				"url.path":                  attribute.StringValue("/users/1"),
+				"url.scheme":                attribute.StringValue("https"),
+				"server.address":            attribute.StringValue("example.com"),
+				"server.port":               attribute.IntValue(8443),
+				"client.address":            attribute.StringValue("192.0.2.1"),
+				"user_agent.original":       attribute.StringValue("test"),
+				"network.protocol.version":  attribute.StringValue("1.1"),
+			},
+			wantStatus: codes.Unset,
+		},
+		{
+			name:   "Trace is continued from the request headers",
+			method: http.MethodGet,
+			url:    "/users/1",
+			header: http.Header{
+				"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			},
+			wantName: "GET /users/{id}",
+			wantAttributes: map[attribute.Key]attribute.Value{
+				"http.response.status_code": attribute.IntValue(http.StatusOK),
+			},
+			wantStatus:   codes.Unset,
+			wantTraceId:  "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantParentId: "00f067aa0ba902b7",
+		},
+		{
+			name:       "Client error response is not a span error",
+			method:     http.MethodGet,
+			url:        "/errors/404",
+			wantName:   "GET /errors/{status}",
+			wantStatus: codes.Unset,
+			wantEvents: []string{"error_response"},
+		},
+		{
+			name:            "Server error response is a span error",
+			method:          http.MethodGet,
+			url:             "/errors/503",
+			wantName:        "GET /errors/{status}",
+			wantStatus:      codes.Error,
+			wantDescription: "Service Unavailable",
+			wantEvents:      []string{"error_response"},
+		},
+		{
+			name:       "Server error status is a span error",
+			method:     http.MethodGet,
+			url:        "/unavailable",
+			wantName:   "GET /unavailable",
+			wantStatus: codes.Error,
+		},
+		{
+			name:            "Panic is recorded",
+			method:          http.MethodGet,
+			url:             "/panic",
+			wantName:        "GET /panic",
+			wantStatus:      codes.Error,
+			wantDescription: "panic",
+			wantEvents:      []string{"exception"},
+			wantPanic:       true,
+		},
+		{
+			name:       "Unrouted request",
+			method:     http.MethodGet,
+			url:        "/missing",
+			wantName:   "GET",
+			wantStatus: codes.Unset,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			router := chi.NewRouter()
+			router.Use(NewOtelMiddleware(WithOtelTracerProvider(provider), WithOtelPropagator(propagation.TraceContext{})))
+			router.Post("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte("created"))
+			})
+			router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+			router.Get("/errors/{status}", func(w http.ResponseWriter, r *http.Request) {
+				status := http.StatusNotFound
+				if chi.URLParam(r, "status") == "503" {
+					status = http.StatusServiceUnavailable
+				}
+				e := response.NewError(status)
+				RecordErrorResponse(r.Context(), e)
+				_ = e.JsonResponse().WriteTo(w)
+			})
+			router.Get("/unavailable", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+			router.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+				panic("test panic")
+			})
+
+			r := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.header != nil {
+				r.Header = tt.header
+			}
+			serve := func() { router.ServeHTTP(httptest.NewRecorder(), r) }
+			if tt.wantPanic {
+				assert.PanicsWithValue(t, "test panic", serve)
+			} else {
+				assert.NotPanics(t, serve)
+			}
+
+			spans := recorder.Ended()
+			if !assert.Len(t, spans, 1) {
+				return
+			}
+			span := spans[0]
+			assert.Equal(t, tt.wantName, span.Name())
+			assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+			assert.Equal(t, tt.wantStatus, span.Status().Code)
+			assert.Equal(t, tt.wantDescription, span.Status().Description)
+
+			attrs := map[attribute.Key]attribute.Value{}
+			for _, kv := range span.Attributes() {
+				attrs[kv.Key] = kv.Value
+			}
+			for k, v := range tt.wantAttributes {
+				assert.Equalf(t, v, attrs[k], "attribute %s", k)
+			}
+			assert.NotContains(t, attrs, attribute.Key("url.query"))
+
+			var events []string
+			for _, e := range span.Events() {
+				events = append(events, e.Name)
+			}
+			assert.Equal(t, tt.wantEvents, events)
+
+			if tt.wantTraceId != "" {
+				assert.Equal(t, tt.wantTraceId, span.SpanContext().TraceID().String())
+				assert.Equal(t, tt.wantParentId, span.Parent().SpanID().String())
+			}
+		})
+	}
+}
@@ -2,8 +2,12 @@ package middleware
 
 import (
 	"context"
-	"github.com/google/uuid"
+	"github.com/ellogroup/ello-golang-http/response"
+	"go.uber.org/zap"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
 )
 
 var RequestIDHeader = "X-Request-Id"
@@ -12,20 +16,106 @@ type requestIDKey struct{}
 
 var RequestIDCtxKey = &requestIDKey{}
 
+// RequestIdOption configures the request id middleware.
+type RequestIdOption func(*requestIdConfig)
+
+// WithRequestIdMaxLength sets the maximum length of a request id passed in the request headers.
+func WithRequestIdMaxLength(n int) RequestIdOption {
+	return func(c *requestIdConfig) {
+		c.maxLength = n
+	}
+}
+
+// WithRequestIdCharset sets the characters allowed in a request id passed in the request headers.
+func WithRequestIdCharset(chars string) RequestIdOption {
+	return func(c *requestIdConfig) {
+		c.charset = chars
+	}
+}
+
+// WithRequestIdReject rejects requests with an invalid request id in the request headers with a 400 Bad Request
+// response. By default, an invalid request id is replaced with a generated one.
+func WithRequestIdReject() RequestIdOption {
+	return func(c *requestIdConfig) {
+		c.reject = true
+	}
+}
+
+// WithRequestIdTrustedProxies sets the networks of the proxies trusted to pass a request id in the request headers,
+// matched against the remote address of the request. A request id passed by any other client is ignored. By default,
+// all clients are trusted.
+func WithRequestIdTrustedProxies(prefixes ...netip.Prefix) RequestIdOption {
+	return func(c *requestIdConfig) {
+		c.trustedProxies = append(c.trustedProxies, prefixes...)
+	}
+}
+
+// WithRequestIdGenerator sets the generator of request ids, which is UUIDv4 by default.
+func WithRequestIdGenerator(gen RequestIdGenerator) RequestIdOption {
+	return func(c *requestIdConfig) {
+		c.generator = gen
+	}
+}
+
+// WithRequestIdAlternateHeaders sets the request headers checked for a request id, in order, when RequestIDHeader is
+// not passed (e.g. X-Correlation-Id). The request id is always returned in RequestIDHeader.
+func WithRequestIdAlternateHeaders(headers ...string) RequestIdOption {
+	return func(c *requestIdConfig) {
+		c.alternateHeaders = append(c.alternateHeaders, headers...)
+	}
+}
+
+type requestIdConfig struct {
+	maxLength        int
+	charset          string
+	reject           bool
+	trustedProxies   []netip.Prefix
+	generator        RequestIdGenerator
+	alternateHeaders []string
+}
+
 // NewRequestIdMiddleware returns a handler to be used as middleware. This middleware will add a request id to the
 // request context. If a request id has been passed in the request headers this will be used, otherwise a random UUID
-// will be generated instead.
+// will be generated instead. Options can restrict which request ids are accepted, and from which clients.
+//
+// A request id already in the request context is kept, e.g. the request id given to each sub-request of a batch, as it
+// was set by this process rather than the client.
 //
 // If used, it is recommended this is one of the first middleware in the chain so all following processes have a
 // request id.
-func NewRequestIdMiddleware() func(http.Handler) http.Handler {
+func NewRequestIdMiddleware(opts ...RequestIdOption) func(http.Handler) http.Handler {
+	c := &requestIdConfig{
+		generator: UUIDv4,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Keep a request id already in the context
+			if requestId := RequestId(r.Context()); requestId != "" {
+				w.Header().Set(RequestIDHeader, requestId)
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// Fetch request id from header
-			requestId := r.Header.Get(RequestIDHeader)
+			requestId := c.fromHeaders(r)
+			if requestId != "" && !c.valid(requestId) {
+				if c.reject {
+					Log(r.Context(), zap.DebugLevel, "Invalid request id provided", zap.String("request_id", requestId))
+					err := response.NewError(http.StatusBadRequest).WithMessage("Invalid request id").JsonResponse().WriteTo(w)
+					if err != nil {
+						// Unable to write the response to the response writer
+						Log(r.Context(), zap.ErrorLevel, "Unable to write response", zap.Error(err))
+					}
+					return
+				}
+				requestId = ""
+			}
 			if requestId == "" {
 				// Request id not found, generate one
-				requestId = uuid.New().String()
+				requestId = c.generator()
 			}
 
 			// Add to context
@@ -41,6 +131,53 @@ func NewRequestIdMiddleware() func(http.Handler) http.Handler {
 	}
 }
 
+func (c *requestIdConfig) fromHeaders(r *http.Request) string {
+	if !c.trusted(r) {
+		return ""
+	}
+	if requestId := r.Header.Get(RequestIDHeader); requestId != "" {
+		return requestId
+	}
+	for _, h := range c.alternateHeaders {
+		if requestId := r.Header.Get(h); requestId != "" {
+			return requestId
+		}
+	}
+	return ""
+}
+
+// trusted returns whether the client is trusted to pass a request id in the request headers.
+func (c *requestIdConfig) trusted(r *http.Request) bool {
+	if len(c.trustedProxies) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range c.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *requestIdConfig) valid(requestId string) bool {
+	if c.maxLength > 0 && len(requestId) > c.maxLength {
+		return false
+	}
+	if c.charset != "" && strings.Trim(requestId, c.charset) != "" {
+		return false
+	}
+	return true
+}
+
 // RequestId will extract the request id from the request context. If the request id is not set in the context an empty
 // string will be returned.
 func RequestId(ctx context.Context) string {
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/google/uuid"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// RequestIdGenerator generates a new request id.
+type RequestIdGenerator func() string
+
+// UUIDv4 generates a random UUID (version 4) request id. This is the default generator.
+func UUIDv4() string {
+	return uuid.New().String()
+}
+
+// UUIDv7 generates a time-ordered UUID (version 7) request id.
+func UUIDv7() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates a time-ordered ULID request id, a 48-bit millisecond timestamp followed by 80 random bits, encoded as
+// 26 characters of Crockford base32.
+func ULID() string {
+	return ulid(time.Now())
+}
+
+// ulid generates a ULID with the timestamp t.
+func ulid(t time.Time) string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(t.UnixMilli())<<16)
+	_, _ = rand.Read(b[6:])
+
+	// 128 bits are encoded as 26 characters of 5 bits, with the first character holding the top 3 bits
+	n := new(big.Int).SetBytes(b[:])
+	mask := big.NewInt(31)
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, 5)
+	}
+	return string(out)
+}
+
+const (
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	ksuidEpoch     = 1400000000
+)
+
+// KSUID generates a time-ordered KSUID request id, a 32-bit timestamp in seconds since the KSUID epoch followed by 128
+// random bits, encoded as 27 characters of base62.
+func KSUID() string {
+	return ksuid(time.Now())
+}
+
+// ksuid generates a KSUID with the timestamp t.
+func ksuid(t time.Time) string {
+	var b [20]byte
+	binary.BigEndian.PutUint32(b[:4], uint32(t.Unix()-ksuidEpoch))
+	_, _ = rand.Read(b[4:])
+
+	n := new(big.Int).SetBytes(b[:])
+	base := big.NewInt(62)
+	mod := new(big.Int)
+	out := []byte(strings.Repeat("0", 27))
+	for i := 26; i >= 0 && n.Sign() > 0; i-- {
+		n.DivMod(n, base, mod)
+		out[i] = base62Alphabet[mod.Int64()]
+	}
+	return string(out)
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestIdGenerators(t *testing.T) {
+	tests := []struct {
+		name      string
+		gen       RequestIdGenerator
+		wantMatch *regexp.Regexp
+	}{
+		{
+			name:      "UUIDv4",
+			gen:       UUIDv4,
+			wantMatch: regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$"),
+		},
+		{
+			name:      "UUIDv7",
+			gen:       UUIDv7,
+			wantMatch: regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$"),
+		},
+		{
+			name:      "ULID",
+			gen:       ULID,
+			wantMatch: regexp.MustCompile("^[0-7][0-9A-HJKMNP-TV-Z]{25}$"),
+		},
+		{
+			name:      "KSUID",
+			gen:       KSUID,
+			wantMatch: regexp.MustCompile("^[0-9A-Za-z]{27}$"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := tt.gen(), tt.gen()
+			assert.Regexp(t, tt.wantMatch, a)
+			assert.NotEqual(t, a, b)
+		})
+	}
+}
+
+func TestRequestIdGenerators_Timestamp(t *testing.T) {
+	// Timestamps from the ULID and KSUID specifications
+	assert.Equal(t, "01ARYZ6S41", ulid(time.UnixMilli(1469918176385))[:10])
+
+	n := new(big.Int)
+	for _, c := range ksuid(time.Unix(1494985761, 0)) {
+		n.Mul(n, big.NewInt(62))
+		n.Add(n, big.NewInt(int64(strings.IndexRune(base62Alphabet, c))))
+	}
+	b := n.FillBytes(make([]byte, 20))
+	assert.Equal(t, uint32(94985761), binary.BigEndian.Uint32(b[:4]))
+}
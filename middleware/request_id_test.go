@@ -5,6 +5,8 @@ import (
 	"github.com/ellogroup/ello-golang-http/internal/mock"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
 	"regexp"
 	"testing"
 )
@@ -56,6 +58,133 @@ func TestNewRequestIdMiddleware(t *testing.T) {
 	}
 }
 
+func TestNewRequestIdMiddleware_Options(t *testing.T) {
+	uuidMatch := regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$")
+	tests := []struct {
+		name       string
+		opts       []RequestIdOption
+		remoteAddr string
+		header     http.Header
+		requestId  string
+		wantStatus int
+		wantMatch  *regexp.Regexp
+	}{
+		{
+			name:      "Valid request id is used",
+			opts:      []RequestIdOption{WithRequestIdMaxLength(8), WithRequestIdCharset("abc-")},
+			header:    http.Header{RequestIDHeader: {"abc-cba"}},
+			wantMatch: regexp.MustCompile("^abc-cba$"),
+		},
+		{
+			name:      "Request id too long is replaced",
+			opts:      []RequestIdOption{WithRequestIdMaxLength(4)},
+			header:    http.Header{RequestIDHeader: {"abc-cba"}},
+			wantMatch: uuidMatch,
+		},
+		{
+			name:      "Request id with invalid characters is replaced",
+			opts:      []RequestIdOption{WithRequestIdCharset("abc-")},
+			header:    http.Header{RequestIDHeader: {"abc\ncba"}},
+			wantMatch: uuidMatch,
+		},
+		{
+			name:       "Invalid request id is rejected",
+			opts:       []RequestIdOption{WithRequestIdMaxLength(4), WithRequestIdReject()},
+			header:     http.Header{RequestIDHeader: {"abc-cba"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Request id from trusted proxy is used",
+			opts:       []RequestIdOption{WithRequestIdTrustedProxies(netip.MustParsePrefix("10.0.0.0/8"))},
+			remoteAddr: "10.1.2.3:1234",
+			header:     http.Header{RequestIDHeader: {"test-abc"}},
+			wantMatch:  regexp.MustCompile("^test-abc$"),
+		},
+		{
+			name:       "Request id from IPv4-mapped trusted proxy is used",
+			opts:       []RequestIdOption{WithRequestIdTrustedProxies(netip.MustParsePrefix("10.0.0.0/8"))},
+			remoteAddr: "[::ffff:10.1.2.3]:1234",
+			header:     http.Header{RequestIDHeader: {"test-abc"}},
+			wantMatch:  regexp.MustCompile("^test-abc$"),
+		},
+		{
+			name:       "Request id from untrusted client is ignored",
+			opts:       []RequestIdOption{WithRequestIdTrustedProxies(netip.MustParsePrefix("10.0.0.0/8"))},
+			remoteAddr: "192.168.1.1:1234",
+			header:     http.Header{RequestIDHeader: {"test-abc"}},
+			wantMatch:  uuidMatch,
+		},
+		{
+			name: "Invalid request id from untrusted client is ignored",
+			opts: []RequestIdOption{
+				WithRequestIdTrustedProxies(netip.MustParsePrefix("10.0.0.0/8")),
+				WithRequestIdMaxLength(4),
+				WithRequestIdReject(),
+			},
+			remoteAddr: "192.168.1.1:1234",
+			header:     http.Header{RequestIDHeader: {"abc-cba"}},
+			wantMatch:  uuidMatch,
+		},
+		{
+			name: "Request id in context is kept",
+			opts: []RequestIdOption{
+				WithRequestIdTrustedProxies(netip.MustParsePrefix("10.0.0.0/8")),
+				WithRequestIdMaxLength(4),
+				WithRequestIdReject(),
+			},
+			remoteAddr: "192.168.1.1:1234",
+			header:     http.Header{RequestIDHeader: {"test-abc"}},
+			requestId:  "parent-1",
+			wantMatch:  regexp.MustCompile("^parent-1$"),
+		},
+		{
+			name:      "Alternate header is used",
+			opts:      []RequestIdOption{WithRequestIdAlternateHeaders("X-Correlation-Id", "X-Amzn-Trace-Id")},
+			header:    http.Header{"X-Amzn-Trace-Id": {"trace"}, "X-Correlation-Id": {"correlation"}},
+			wantMatch: regexp.MustCompile("^correlation$"),
+		},
+		{
+			name:      "Request id header takes precedence",
+			opts:      []RequestIdOption{WithRequestIdAlternateHeaders("X-Correlation-Id")},
+			header:    http.Header{RequestIDHeader: {"request"}, "X-Correlation-Id": {"correlation"}},
+			wantMatch: regexp.MustCompile("^request$"),
+		},
+		{
+			name:      "Custom generator",
+			opts:      []RequestIdOption{WithRequestIdGenerator(func() string { return "generated" })},
+			header:    http.Header{},
+			wantMatch: regexp.MustCompile("^generated$"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = RequestId(r.Context())
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header = tt.header
+			if tt.remoteAddr != "" {
+				r.RemoteAddr = tt.remoteAddr
+			}
+			if tt.requestId != "" {
+				r = r.WithContext(context.WithValue(r.Context(), RequestIDCtxKey, tt.requestId))
+			}
+			rec := httptest.NewRecorder()
+			NewRequestIdMiddleware(tt.opts...)(next).ServeHTTP(rec, r)
+
+			if tt.wantStatus != 0 {
+				assert.Equal(t, tt.wantStatus, rec.Code)
+				assert.Empty(t, got, "next handler is not called")
+				return
+			}
+			assert.Regexp(t, tt.wantMatch, got)
+			assert.Equal(t, got, rec.Header().Get(RequestIDHeader))
+		})
+	}
+}
+
 func TestRequestId(t *testing.T) {
 	type args struct {
 		ctx context.Context
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// requestLogger writes log entries to a zap or slog logger, so that the middleware can support both. Fields are zap
+// fields, as used by logctx, which are converted to attributes for slog.
+type requestLogger interface {
+	// Log writes a log entry, at the time given if not zero.
+	Log(ctx context.Context, t time.Time, level zapcore.Level, msg string, fields ...zap.Field)
+}
+
+type zapRequestLogger struct {
+	log *zap.Logger
+}
+
+func (l zapRequestLogger) Log(_ context.Context, t time.Time, level zapcore.Level, msg string, fields ...zap.Field) {
+	if ce := l.log.Check(level, msg); ce != nil {
+		if !t.IsZero() {
+			ce.Time = t
+		}
+		ce.Write(fields...)
+	}
+}
+
+type slogRequestLogger struct {
+	log *slog.Logger
+}
+
+func (l slogRequestLogger) Log(ctx context.Context, t time.Time, level zapcore.Level, msg string, fields ...zap.Field) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	lvl := slogLevel(level)
+	if !l.log.Enabled(ctx, lvl) {
+		return
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	record := slog.NewRecord(t, lvl, msg, 0)
+	record.AddAttrs(slogAttrs(fields)...)
+	_ = l.log.Handler().Handle(ctx, record)
+}
+
+// slogLevel returns the slog level of a zap level. Levels above error are logged at error level.
+func slogLevel(level zapcore.Level) slog.Level {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case level == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case level == zapcore.WarnLevel:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+// slogAttrs returns zap fields as slog attributes, in order. The keys added by a single field are sorted.
+func slogAttrs(fields []zap.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		// A field may add more than one key, e.g. errors with verbose details
+		keys := make([]string, 0, len(enc.Fields))
+		for k := range enc.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			attrs = append(attrs, slog.Any(k, enc.Fields[k]))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// verboseError is an error with verbose details, which zap adds as a second key.
+type verboseError struct{}
+
+func (verboseError) Error() string { return "failed" }
+
+func (e verboseError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		_, _ = fmt.Fprint(s, "failed\ndetails")
+		return
+	}
+	_, _ = fmt.Fprint(s, e.Error())
+}
+
+func Test_slogAttrs(t *testing.T) {
+	fields := []zap.Field{
+		zap.String("z", "last"),
+		zap.Error(verboseError{}),
+		zap.Int("a", 1),
+		zap.NamedError("cause", errors.New("plain")),
+		zap.Duration("duration", time.Second),
+	}
+	want := []string{"z", "error", "errorVerbose", "a", "cause", "duration"}
+
+	// Repeated, as the keys added by a field are encoded to a map, which has no order
+	for i := 0; i < 20; i++ {
+		var keys []string
+		for _, attr := range slogAttrs(fields) {
+			keys = append(keys, attr.Key)
+		}
+		assert.Equal(t, want, keys)
+	}
+	assert.Equal(t, slog.Any("errorVerbose", "failed\ndetails"), slogAttrs(fields)[2])
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	chi_middleware "github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type slogLoggerKey struct{}
+
+var SlogLoggerCtxKey = &slogLoggerKey{}
+
+// NewSlogLoggerMiddleware returns a handler to be used as middleware, the same as NewZapLoggerMiddleware but adding a
+// log/slog logger to the context of the request. This logger can be extracted from the request context with SlogLogger.
+//
+// If used, it is recommended this is one of the first middleware in the chain so all following processes have access
+// to a logger with the request details set. However, the request id middleware should always come _before_ this
+// middleware.
+func NewSlogLoggerMiddleware(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Add context to logger
+			requestLog := log.With(
+				slog.String("http_proto", r.Proto),
+				slog.String("http_method", r.Method),
+				slog.String("request_uri", r.RequestURI),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			)
+
+			// Add request id to logger context
+			if requestId := RequestId(r.Context()); requestId != "" {
+				requestLog = requestLog.With(slog.String("request_id", requestId))
+			}
+
+			// Log request info
+			requestLog.InfoContext(r.Context(), "Request started")
+
+			// Add logger to context
+			ctx := context.WithValue(r.Context(), SlogLoggerCtxKey, requestLog)
+
+			// Wrap the response writer, so we can access details of the response, such as status code
+			ww := chi_middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			// Log the response details
+			requestStart := time.Now()
+			defer func() {
+				// Log response info
+				requestLog.InfoContext(
+					ctx,
+					"Request complete",
+					slog.Int("status_code", responseStatus(ww)),
+					slog.Int("bytes_written", ww.BytesWritten()),
+					slog.Duration("duration", time.Since(requestStart)),
+				)
+			}()
+
+			// Call the next handler in the chain, passing the response writer and
+			// the updated request object with the new context value.
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+// SlogLoggerOrError will extract the slog logger from the request context. If the logger is not set in the context an
+// error will be returned.
+func SlogLoggerOrError(ctx context.Context) (*slog.Logger, error) {
+	if ctx == nil {
+		return nil, errors.New("context is required")
+	}
+	if log, ok := ctx.Value(SlogLoggerCtxKey).(*slog.Logger); ok {
+		return log, nil
+	}
+	return nil, errors.New("slog logger not found within context")
+}
+
+// SlogLogger will extract the slog logger from the request context. If the logger is not set in the context a logger
+// discarding all log entries will be returned.
+func SlogLogger(ctx context.Context) *slog.Logger {
+	if log, err := SlogLoggerOrError(ctx); err == nil {
+		return log
+	}
+	return slog.New(discardHandler{})
+}
+
+// discardHandler is a slog handler discarding all log entries.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSlogLoggerOrError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		want    *slog.Logger
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "Logger within context returns successfully",
+			ctx:     context.WithValue(context.Background(), SlogLoggerCtxKey, logger),
+			want:    logger,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "Non-logger within context returns error",
+			ctx:     context.WithValue(context.Background(), SlogLoggerCtxKey, "not a logger"),
+			wantErr: assert.Error,
+		},
+		{
+			name:    "No logger within context returns error",
+			ctx:     context.Background(),
+			wantErr: assert.Error,
+		},
+		{
+			name:    "nil context returns error",
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SlogLoggerOrError(tt.ctx)
+			if !tt.wantErr(t, err, fmt.Sprintf("SlogLoggerOrError(%v)", tt.ctx)) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "SlogLoggerOrError(%v)", tt.ctx)
+		})
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	assert.Equal(t, logger, SlogLogger(context.WithValue(context.Background(), SlogLoggerCtxKey, logger)))
+
+	// Not in context, log entries are discarded
+	discard := SlogLogger(context.Background())
+	assert.False(t, discard.Enabled(context.Background(), slog.LevelError))
+	discard.Error("test")
+}
+
+func TestNewSlogLoggerMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := NewRequestIdMiddleware()(NewSlogLoggerMiddleware(log)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			SlogLogger(r.Context()).Info("Handling")
+			w.WriteHeader(http.StatusCreated)
+		},
+	)))
+	req := httptest.NewRequest(http.MethodPost, "/orders?page=2", nil)
+	req.Header.Set(RequestIDHeader, "request-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := decodeSlogEntries(t, &buf)
+	if !assert.Len(t, entries, 3) {
+		return
+	}
+	for i, msg := range []string{"Request started", "Handling", "Request complete"} {
+		assert.Equal(t, msg, entries[i]["msg"])
+		assert.Equal(t, "POST", entries[i]["http_method"])
+		assert.Equal(t, "/orders?page=2", entries[i]["request_uri"])
+		assert.Equal(t, "request-1", entries[i]["request_id"])
+	}
+	assert.Equal(t, float64(http.StatusCreated), entries[2]["status_code"])
+	assert.Contains(t, entries[2], "duration")
+}
+
+func TestNewSlogLoggerMiddleware_NothingWritten(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := NewSlogLoggerMiddleware(log)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	entries := decodeSlogEntries(t, &buf)
+	if assert.Len(t, entries, 2) {
+		// The server will respond with 200 OK, as logged by NewSlogLogCtxMiddleware
+		assert.Equal(t, float64(http.StatusOK), entries[1]["status_code"])
+	}
+}
+
+func decodeSlogEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if assert.NoError(t, json.Unmarshal([]byte(line), &entry)) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/ellogroup/ello-golang-http/internal/recorder"
+	"github.com/ellogroup/ello-golang-http/response"
+	"go.uber.org/zap"
+	"mime"
+	"net/http"
+)
+
+// NewSparseFieldsMiddleware returns a handler to be used as middleware. This middleware will prune successful JSON
+// responses to the fields requested in the `fields` query parameter (e.g. `?fields=id,name,address.city`), returning a
+// 400 Bad Request error if an unknown field is requested. Fields are known from the response body, so a field that is
+// omitted from every item can not be requested; use response.WithSparseFields in the handler to check against the type
+// of the body instead.
+func NewSparseFieldsMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("fields") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := recorder.New()
+			next.ServeHTTP(rec, r)
+
+			mediaType, _, _ := mime.ParseMediaType(rec.HeaderMap.Get("Content-Type"))
+			var err error
+			if mediaType != "application/json" || rec.StatusCode < 200 || rec.StatusCode > 299 || rec.Body.Len() == 0 {
+				err = rec.WriteTo(w)
+			} else {
+				resp := response.NewJson(rec.StatusCode, json.RawMessage(rec.Body.Bytes()))
+				resp.Headers = rec.HeaderMap.Clone()
+				// The pruned body is a different representation, so the length and validators no longer apply
+				resp.Headers.Del("Content-Length")
+				resp.Headers.Del("ETag")
+				err = resp.WithSparseFields(r).WriteTo(w)
+			}
+			if err != nil {
+				// Unable to write the response to the response writer
+				Log(r.Context(), zap.ErrorLevel, "Unable to write response", zap.Error(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewSparseFieldsMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		status      int
+		contentType string
+		body        string
+		wantStatus  int
+		wantBody    string
+		wantETag    string
+	}{
+		{
+			name:        "Fields are pruned",
+			url:         "/?fields=id,address.city",
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"id":1,"name":"test","address":{"city":"London","postcode":"N1"}}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"id":1,"address":{"city":"London"}}`,
+		},
+		{
+			name:        "Unknown fields return bad request",
+			url:         "/?fields=id,email",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"id":1,"name":"test"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody: `{"error":{"status":400,"code":"bad_request","message":"Unknown fields requested",` +
+				`"meta":{"fields":"unknown fields: email"}}}`,
+		},
+		{
+			name:        "Responses are unchanged without fields",
+			url:         "/",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"id":1,"name":"test"}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"id":1,"name":"test"}`,
+			wantETag:    `"abc"`,
+		},
+		{
+			name:        "Error responses are unchanged",
+			url:         "/?fields=id",
+			status:      http.StatusNotFound,
+			contentType: "application/json",
+			body:        `{"code":"not_found"}`,
+			wantStatus:  http.StatusNotFound,
+			wantBody:    `{"code":"not_found"}`,
+			wantETag:    `"abc"`,
+		},
+		{
+			name:        "Other content types are unchanged",
+			url:         "/?fields=id",
+			status:      http.StatusOK,
+			contentType: "text/plain",
+			body:        `{"id":1,"name":"test"}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"id":1,"name":"test"}`,
+			wantETag:    `"abc"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("ETag", `"abc"`)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			rec := httptest.NewRecorder()
+			NewSparseFieldsMiddleware()(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			assert.Equal(t, tt.wantETag, rec.Header().Get("ETag"))
+		})
+	}
+}
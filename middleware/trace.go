@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	chi_middleware "github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const TraceparentHeader = "traceparent"
+const TracestateHeader = "tracestate"
+
+// traceFlagSampled is the sampled flag of the trace flags.
+const traceFlagSampled = 0x01
+
+type traceKey struct{}
+
+var TraceCtxKey = &traceKey{}
+
+// TraceContext is the W3C Trace Context of a request. SpanId is the id of the span of the request being processed by
+// this service, and is passed as the parent id to downstream services.
+type TraceContext struct {
+	TraceId    string
+	SpanId     string
+	Flags      byte
+	TraceState string
+}
+
+// Sampled returns whether the trace is sampled, i.e. the caller may have recorded trace data.
+func (t TraceContext) Sampled() bool {
+	return t.Flags&traceFlagSampled != 0
+}
+
+// Traceparent returns the value of the traceparent header for the trace context.
+func (t TraceContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", t.TraceId, t.SpanId, t.Flags)
+}
+
+// Span is a completed span of a request, passed to a SpanExporter.
+type Span struct {
+	TraceContext
+	ParentSpanId string
+	Name         string
+	Start        time.Time
+	End          time.Time
+	StatusCode   int
+}
+
+// SpanExporter exports the spans of sampled requests, e.g. to a tracing collector. ExportSpan is called once the
+// request is complete, before the response is returned, so any slow work should be done asynchronously.
+type SpanExporter interface {
+	ExportSpan(span Span)
+}
+
+// TraceOption configures the trace context middleware.
+type TraceOption func(*traceConfig)
+
+// WithTraceExporter sets the exporter of the spans of sampled requests. By default, spans are not exported.
+func WithTraceExporter(exporter SpanExporter) TraceOption {
+	return func(c *traceConfig) {
+		c.exporter = exporter
+	}
+}
+
+type traceConfig struct {
+	exporter SpanExporter
+}
+
+// NewTraceContextMiddleware returns a handler to be used as middleware. This middleware will add a W3C Trace Context to
+// the request context, continuing the trace from the traceparent and tracestate request headers if valid, otherwise
+// starting a new sampled trace. A new span id is generated for each request. The trace context is returned in the
+// traceparent response header, and the trace and span ids are added to the logctx fields of the request.
+//
+// If used, it is recommended this comes directly after the request id middleware.
+func NewTraceContextMiddleware(opts ...TraceOption) func(http.Handler) http.Handler {
+	c := &traceConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent, ok := ParseTraceparent(r.Header.Get(TraceparentHeader))
+			if ok {
+				parent.TraceState = r.Header.Get(TracestateHeader)
+			} else {
+				// Traceparent not found or invalid, start a new trace
+				parent = TraceContext{TraceId: newTraceId(), Flags: traceFlagSampled}
+			}
+			trace := parent
+			trace.SpanId = newSpanId()
+
+			// Add to context
+			ctx := context.WithValue(r.Context(), TraceCtxKey, trace)
+			ctx = addLogCtxString(ctx, "trace_id", trace.TraceId)
+			ctx = addLogCtxString(ctx, "span_id", trace.SpanId)
+
+			// Add to response headers
+			w.Header().Set(TraceparentHeader, trace.Traceparent())
+
+			if c.exporter == nil || !trace.Sampled() {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			// Wrap the response writer, so we can access the status code for the span
+			ww := chi_middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			span := Span{
+				TraceContext: trace,
+				ParentSpanId: parent.SpanId,
+				Start:        time.Now(),
+			}
+			defer func() {
+				span.End = time.Now()
+				span.Name = spanName(r)
+				span.StatusCode = responseStatus(ww)
+				c.exporter.ExportSpan(span)
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+// Trace will extract the trace context from the request context. If the trace context is not set in the context a zero
+// value will be returned.
+func Trace(ctx context.Context) TraceContext {
+	if ctx == nil {
+		return TraceContext{}
+	}
+	trace, _ := ctx.Value(TraceCtxKey).(TraceContext)
+	return trace
+}
+
+// InjectTrace sets the traceparent and tracestate headers of an outgoing request from the trace context of ctx, so the
+// trace is continued by the downstream service.
+func InjectTrace(ctx context.Context, header http.Header) {
+	trace := Trace(ctx)
+	if trace.TraceId == "" {
+		return
+	}
+	header.Set(TraceparentHeader, trace.Traceparent())
+	if trace.TraceState != "" {
+		header.Set(TracestateHeader, trace.TraceState)
+	}
+}
+
+// ParseTraceparent parses the value of a traceparent header, returning false if it is not valid. The SpanId of the
+// returned trace context is the parent id of the header.
+func ParseTraceparent(value string) (TraceContext, bool) {
+	value = strings.TrimSpace(value)
+	// Future versions may append fields, which are ignored
+	if len(value) < 55 || (len(value) > 55 && (value[:2] == "00" || value[55] != '-')) {
+		return TraceContext{}, false
+	}
+	version, traceId, spanId, flags := value[:2], value[3:35], value[36:52], value[53:55]
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return TraceContext{}, false
+	}
+	if !isLowerHex(version) || version == "ff" || !isLowerHex(traceId) || !isLowerHex(spanId) || !isLowerHex(flags) {
+		return TraceContext{}, false
+	}
+	if traceId == strings.Repeat("0", 32) || spanId == strings.Repeat("0", 16) {
+		return TraceContext{}, false
+	}
+	f, _ := hex.DecodeString(flags)
+	return TraceContext{TraceId: traceId, SpanId: spanId, Flags: f[0]}, true
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func newTraceId() string {
+	return randomHex(16)
+}
+
+func newSpanId() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// spanName returns the name of the span of a request, the method and the chi route pattern if routed.
+func spanName(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return r.Method + " " + rctx.RoutePattern()
+	}
+	return r.Method
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+type spanRecorder struct {
+	spans []Span
+}
+
+func (s *spanRecorder) ExportSpan(span Span) {
+	s.spans = append(s.spans, span)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   TraceContext
+		wantOk bool
+	}{
+		{
+			name:   "Valid traceparent",
+			value:  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			want:   TraceContext{TraceId: "4bf92f3577b34da6a3ce929d0e0e4736", SpanId: "00f067aa0ba902b7", Flags: 0x01},
+			wantOk: true,
+		},
+		{
+			name:   "Not sampled",
+			value:  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			want:   TraceContext{TraceId: "4bf92f3577b34da6a3ce929d0e0e4736", SpanId: "00f067aa0ba902b7"},
+			wantOk: true,
+		},
+		{
+			name:   "Future version with extra fields",
+			value:  "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-09-extra",
+			want:   TraceContext{TraceId: "4bf92f3577b34da6a3ce929d0e0e4736", SpanId: "00f067aa0ba902b7", Flags: 0x09},
+			wantOk: true,
+		},
+		{name: "Empty", value: ""},
+		{name: "Version 00 with extra fields", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "Invalid version", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "Upper case", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "Zero trace id", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "Zero parent id", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "Invalid separator", value: "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "Too short", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseTraceparent(tt.value)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewTraceContextMiddleware(t *testing.T) {
+	traceparentMatch := regexp.MustCompile("^00-[0-9a-f]{32}-[0-9a-f]{16}-01$")
+	tests := []struct {
+		name           string
+		header         http.Header
+		wantTraceId    string
+		wantParentId   string
+		wantTraceState string
+		wantSampled    bool
+		wantExported   bool
+	}{
+		{
+			name: "Trace is continued from the request headers",
+			header: http.Header{
+				"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+				"Tracestate":  {"congo=t61rcWkgMzE"},
+			},
+			wantTraceId:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantParentId:   "00f067aa0ba902b7",
+			wantTraceState: "congo=t61rcWkgMzE",
+			wantSampled:    true,
+			wantExported:   true,
+		},
+		{
+			name: "Trace that is not sampled is not exported",
+			header: http.Header{
+				"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+			},
+			wantTraceId:  "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantParentId: "00f067aa0ba902b7",
+		},
+		{
+			name: "Invalid traceparent starts a new trace",
+			header: http.Header{
+				"Traceparent": {"00-invalid"},
+				"Tracestate":  {"congo=t61rcWkgMzE"},
+			},
+			wantSampled:  true,
+			wantExported: true,
+		},
+		{
+			name:         "No traceparent starts a new trace",
+			header:       http.Header{},
+			wantSampled:  true,
+			wantExported: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &spanRecorder{}
+			var got TraceContext
+			router := chi.NewRouter()
+			router.Use(NewTraceContextMiddleware(WithTraceExporter(exporter)))
+			router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				got = Trace(r.Context())
+				w.WriteHeader(http.StatusAccepted)
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			r.Header = tt.header
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, r)
+
+			assert.Regexp(t, "^[0-9a-f]{32}$", got.TraceId)
+			assert.Regexp(t, "^[0-9a-f]{16}$", got.SpanId)
+			assert.NotEqual(t, tt.wantParentId, got.SpanId, "new span id")
+			if tt.wantTraceId != "" {
+				assert.Equal(t, tt.wantTraceId, got.TraceId)
+			}
+			assert.Equal(t, tt.wantTraceState, got.TraceState)
+			assert.Equal(t, tt.wantSampled, got.Sampled())
+			assert.Equal(t, got.Traceparent(), rec.Header().Get(TraceparentHeader))
+			if tt.wantSampled {
+				assert.Regexp(t, traceparentMatch, rec.Header().Get(TraceparentHeader))
+			}
+
+			if !tt.wantExported {
+				assert.Empty(t, exporter.spans)
+				return
+			}
+			if assert.Len(t, exporter.spans, 1) {
+				span := exporter.spans[0]
+				assert.Equal(t, got, span.TraceContext)
+				assert.Equal(t, tt.wantParentId, span.ParentSpanId)
+				assert.Equal(t, "GET /users/{id}", span.Name)
+				assert.Equal(t, http.StatusAccepted, span.StatusCode)
+				assert.False(t, span.End.Before(span.Start))
+			}
+		})
+	}
+}
+
+func TestNewTraceContextMiddleware_NothingWritten(t *testing.T) {
+	exporter := &spanRecorder{}
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	handler := NewTraceContextMiddleware(WithTraceExporter(exporter))(next)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if assert.Len(t, exporter.spans, 1) {
+		assert.Equal(t, http.StatusOK, exporter.spans[0].StatusCode)
+	}
+}
+
+func TestInjectTrace(t *testing.T) {
+	header := http.Header{}
+	InjectTrace(context.Background(), header)
+	assert.Empty(t, header)
+
+	trace := TraceContext{
+		TraceId:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanId:     "00f067aa0ba902b7",
+		Flags:      0x01,
+		TraceState: "congo=t61rcWkgMzE",
+	}
+	InjectTrace(context.WithValue(context.Background(), TraceCtxKey, trace), header)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", header.Get(TraceparentHeader))
+	assert.Equal(t, "congo=t61rcWkgMzE", header.Get(TracestateHeader))
+}
@@ -0,0 +1,118 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-http/query"
+	"github.com/ellogroup/ello-golang-http/response"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// MinCursorKeySize is the minimum size in bytes of the key of a CursorCodec.
+const MinCursorKeySize = 32
+
+// CursorCodec encodes the position of a page into an opaque cursor, signed with HMAC-SHA256 so that it can not be
+// tampered with by clients. The position is encoded as JSON, so should be a small struct of the values needed to
+// resume the list, e.g. the sort key and id of the last item.
+type CursorCodec struct {
+	key []byte
+}
+
+// NewCursorCodec creates a new cursor codec, signing cursors with the key. The key should be random and kept secret.
+// It panics if the key is shorter than MinCursorKeySize, as cursors signed with a short key can be forged.
+func NewCursorCodec(key []byte) *CursorCodec {
+	if len(key) < MinCursorKeySize {
+		panic(fmt.Sprintf("pagination: cursor key must be at least %d bytes, got %d", MinCursorKeySize, len(key)))
+	}
+	return &CursorCodec{key: key}
+}
+
+// Encode encodes the position into a signed cursor.
+func (c *CursorCodec) Encode(position any) (string, error) {
+	payload, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload)),
+		nil
+}
+
+// Decode verifies the signature of the cursor and decodes the position into dst. ErrInvalidCursor is returned if the
+// cursor is malformed or has been tampered with.
+func (c *CursorCodec) Decode(cursor string, dst any) error {
+	encodedPayload, encodedSig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, dst); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Cursor is the position of a page in a list, parsed from the `limit` and `cursor` query parameters.
+type Cursor struct {
+	Limit int
+	// Set reports whether the request included a cursor. If false, the first page should be returned.
+	Set bool
+}
+
+// ParseCursor parses and validates the `limit` and `cursor` query parameters, decoding the position of the cursor
+// into position. The limit defaults to 20 and must be between 1 and 100, unless configured with options.
+//
+// The returned error will be a 400 Bad Request response.ErrorDetails, with the details of each invalid parameter in the
+// meta.
+func (c *CursorCodec) ParseCursor(q url.Values, position any, opts ...Option) (Cursor, error) {
+	conf := newConfig(opts)
+	errs := map[string]error{}
+	cur := Cursor{Limit: parseInt(q, "limit", conf.defaultLimit, "min=1,max="+strconv.Itoa(conf.maxLimit), errs)}
+	if s := q.Get("cursor"); s != "" {
+		if err := c.Decode(s, position); err != nil {
+			errs["cursor"] = err
+		}
+		cur.Set = true
+	}
+	if len(errs) > 0 {
+		return Cursor{}, query.ErrorDetails(errs)
+	}
+	return cur, nil
+}
+
+// Page returns a page of items, with the links to the first and next pages. The next cursor should be empty when
+// there are no more items.
+func (c Cursor) Page(items any, nextCursor string) response.Page {
+	limit := strconv.Itoa(c.Limit)
+	page := response.Page{
+		Items:      items,
+		NextCursor: nextCursor,
+		Links: response.PageLinks{
+			First: map[string]string{"limit": limit, "cursor": ""},
+		},
+	}
+	if nextCursor != "" {
+		page.Links.Next = map[string]string{"limit": limit, "cursor": nextCursor}
+	}
+	return page
+}
@@ -0,0 +1,118 @@
+package pagination
+
+import (
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type position struct {
+	CreatedAt string `json:"c"`
+	Id        int    `json:"i"`
+}
+
+var testCursorKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestNewCursorCodec(t *testing.T) {
+	for _, key := range [][]byte{nil, {}, []byte("secret"), testCursorKey[:MinCursorKeySize-1]} {
+		assert.Panicsf(t, func() { NewCursorCodec(key) }, "NewCursorCodec(%q)", key)
+	}
+	assert.NotPanics(t, func() { NewCursorCodec(testCursorKey) })
+}
+
+func TestCursorCodec_EncodeDecode(t *testing.T) {
+	codec := NewCursorCodec(testCursorKey)
+	want := position{CreatedAt: "2024-03-01T12:00:00Z", Id: 42}
+
+	cursor, err := codec.Encode(want)
+	assert.NoError(t, err)
+	assert.NotContains(t, cursor, "=", "cursor is URL safe")
+
+	var got position
+	assert.NoError(t, codec.Decode(cursor, &got))
+	assert.Equal(t, want, got)
+
+	payload, sig, _ := strings.Cut(cursor, ".")
+	tampered, _ := NewCursorCodec(testCursorKey).Encode(position{Id: 1})
+	tamperedPayload, _, _ := strings.Cut(tampered, ".")
+
+	for name, c := range map[string]string{
+		"Empty":             "",
+		"No signature":      payload,
+		"Invalid encoding":  "!!!." + sig,
+		"Tampered payload":  tamperedPayload + "." + sig,
+		"Different key":     mustEncode(t, NewCursorCodec([]byte(strings.Repeat("o", MinCursorKeySize))), want),
+		"Invalid signature": payload + ".abc",
+	} {
+		assert.ErrorIsf(t, codec.Decode(c, &got), ErrInvalidCursor, name)
+	}
+}
+
+func mustEncode(t *testing.T, codec *CursorCodec, v any) string {
+	s, err := codec.Encode(v)
+	assert.NoError(t, err)
+	return s
+}
+
+func TestCursorCodec_ParseCursor(t *testing.T) {
+	codec := NewCursorCodec(testCursorKey)
+	cursor := mustEncode(t, codec, position{Id: 42})
+
+	tests := []struct {
+		name         string
+		q            url.Values
+		want         Cursor
+		wantPosition position
+		wantMeta     map[string]string
+	}{
+		{
+			name: "First page",
+			q:    url.Values{},
+			want: Cursor{Limit: 20},
+		},
+		{
+			name:         "Cursor and limit are parsed",
+			q:            url.Values{"cursor": {cursor}, "limit": {"5"}},
+			want:         Cursor{Limit: 5, Set: true},
+			wantPosition: position{Id: 42},
+		},
+		{
+			name:     "Invalid cursor and limit",
+			q:        url.Values{"cursor": {"abc"}, "limit": {"1000"}},
+			wantMeta: map[string]string{"cursor": "invalid cursor", "limit": "failed on the 'max=100' rule"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pos position
+			got, err := codec.ParseCursor(tt.q, &pos)
+			if tt.wantMeta == nil {
+				assert.NoError(t, err)
+				assert.Equalf(t, tt.want, got, "ParseCursor()")
+				assert.Equalf(t, tt.wantPosition, pos, "position")
+				return
+			}
+			details := response.AsErrorDetails(err)
+			assert.Equal(t, http.StatusBadRequest, details.Status)
+			assert.Equal(t, tt.wantMeta, details.Meta)
+		})
+	}
+}
+
+func TestCursor_Page(t *testing.T) {
+	got := Cursor{Limit: 10, Set: true}.Page([]int{1, 2}, "next")
+	assert.Equal(t, response.Page{
+		Items:      []int{1, 2},
+		NextCursor: "next",
+		Links: response.PageLinks{
+			First: map[string]string{"limit": "10", "cursor": ""},
+			Next:  map[string]string{"limit": "10", "cursor": "next"},
+		},
+	}, got)
+
+	got = Cursor{Limit: 10}.Page([]int{}, "")
+	assert.Nil(t, got.Links.Next, "no next link on the last page")
+}
@@ -0,0 +1,115 @@
+package pagination
+
+import (
+	"fmt"
+	"github.com/ellogroup/ello-golang-http/query"
+	"github.com/ellogroup/ello-golang-http/response"
+	"net/url"
+	"strconv"
+)
+
+const defaultLimit = 20
+const defaultMaxLimit = 100
+
+var validate = query.NewValidator()
+
+type config struct {
+	defaultLimit int
+	maxLimit     int
+}
+
+type Option func(*config)
+
+// WithDefaultLimit sets the limit used when the request does not include one. Defaults to 20.
+func WithDefaultLimit(limit int) Option {
+	return func(c *config) {
+		c.defaultLimit = limit
+	}
+}
+
+// WithMaxLimit sets the maximum limit that can be requested. Defaults to 100.
+func WithMaxLimit(limit int) Option {
+	return func(c *config) {
+		c.maxLimit = limit
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{defaultLimit: defaultLimit, maxLimit: defaultMaxLimit}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// Offset is the position of a page in a list, parsed from the `limit` and `offset` query parameters.
+type Offset struct {
+	Limit  int
+	Offset int
+}
+
+// ParseOffset parses and validates the `limit` and `offset` query parameters. The limit defaults to 20 and must be
+// between 1 and 100, and the offset defaults to 0, unless configured with options.
+//
+// The returned error will be a 400 Bad Request response.ErrorDetails, with the details of each invalid parameter in the
+// meta.
+func ParseOffset(q url.Values, opts ...Option) (Offset, error) {
+	c := newConfig(opts)
+	errs := map[string]error{}
+	o := Offset{
+		Limit:  parseInt(q, "limit", c.defaultLimit, "min=1,max="+strconv.Itoa(c.maxLimit), errs),
+		Offset: parseInt(q, "offset", 0, "min=0", errs),
+	}
+	if len(errs) > 0 {
+		return Offset{}, query.ErrorDetails(errs)
+	}
+	return o, nil
+}
+
+// parseInt decodes an integer query parameter and validates it against the rules, adding any error to errs. The
+// default value is returned if the parameter is not set.
+func parseInt(q url.Values, param string, def int, rules string, errs map[string]error) int {
+	v := q.Get(param)
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		errs[param] = fmt.Errorf("invalid integer %q", v)
+		return def
+	}
+	if err := validate.ValidateVar(i, rules); err != nil {
+		errs[param] = err
+		return def
+	}
+	return i
+}
+
+// Page returns a page of items at the offset, with the links to the first, previous, next and last pages. The next and
+// last links are only included when the total is known, i.e. not negative.
+func (o Offset) Page(items any, total int) response.Page {
+	page := response.Page{
+		Items: items,
+		Links: response.PageLinks{
+			First: o.params(0),
+		},
+	}
+	if o.Offset > 0 {
+		page.Links.Prev = o.params(max(o.Offset-o.Limit, 0))
+	}
+	if total >= 0 {
+		page.Total = &total
+		if o.Offset+o.Limit < total {
+			page.Links.Next = o.params(o.Offset + o.Limit)
+		}
+		page.Links.Last = o.params(max((total-1)/o.Limit*o.Limit, 0))
+	}
+	return page
+}
+
+func (o Offset) params(offset int) map[string]string {
+	return map[string]string{
+		"limit":  strconv.Itoa(o.Limit),
+		"offset": strconv.Itoa(offset),
+	}
+}
@@ -0,0 +1,150 @@
+package pagination
+
+import (
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		name     string
+		q        url.Values
+		opts     []Option
+		want     Offset
+		wantMeta []string
+	}{
+		{
+			name: "Defaults",
+			q:    url.Values{},
+			want: Offset{Limit: 20},
+		},
+		{
+			name: "Limit and offset are parsed",
+			q:    url.Values{"limit": {"50"}, "offset": {"100"}},
+			want: Offset{Limit: 50, Offset: 100},
+		},
+		{
+			name: "Options set the default limit",
+			q:    url.Values{"offset": {"10"}},
+			opts: []Option{WithDefaultLimit(5)},
+			want: Offset{Limit: 5, Offset: 10},
+		},
+		{
+			name:     "Limit above maximum",
+			q:        url.Values{"limit": {"101"}},
+			wantMeta: []string{"limit"},
+		},
+		{
+			name: "Options set the maximum limit",
+			q:    url.Values{"limit": {"200"}},
+			opts: []Option{WithMaxLimit(500)},
+			want: Offset{Limit: 200},
+		},
+		{
+			name:     "Invalid values",
+			q:        url.Values{"limit": {"0"}, "offset": {"-1"}},
+			wantMeta: []string{"limit", "offset"},
+		},
+		{
+			name:     "Values are not numbers",
+			q:        url.Values{"limit": {"ten"}, "offset": {"first"}},
+			wantMeta: []string{"limit", "offset"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOffset(tt.q, tt.opts...)
+			if tt.wantMeta == nil {
+				assert.NoError(t, err)
+				assert.Equalf(t, tt.want, got, "ParseOffset()")
+				return
+			}
+			details := response.AsErrorDetails(err)
+			assert.Equal(t, http.StatusBadRequest, details.Status)
+			meta, _ := details.Meta.(map[string]string)
+			var gotMeta []string
+			for param := range meta {
+				gotMeta = append(gotMeta, param)
+			}
+			assert.ElementsMatchf(t, tt.wantMeta, gotMeta, "meta")
+		})
+	}
+}
+
+func TestOffset_Page(t *testing.T) {
+	params := func(limit, offset string) map[string]string {
+		return map[string]string{"limit": limit, "offset": offset}
+	}
+	tests := []struct {
+		name      string
+		offset    Offset
+		total     int
+		wantTotal *int
+		wantLinks response.PageLinks
+	}{
+		{
+			name:   "First page",
+			offset: Offset{Limit: 10},
+			total:  25,
+			wantLinks: response.PageLinks{
+				First: params("10", "0"),
+				Next:  params("10", "10"),
+				Last:  params("10", "20"),
+			},
+		},
+		{
+			name:   "Middle page",
+			offset: Offset{Limit: 10, Offset: 15},
+			total:  40,
+			wantLinks: response.PageLinks{
+				First: params("10", "0"),
+				Prev:  params("10", "5"),
+				Next:  params("10", "25"),
+				Last:  params("10", "30"),
+			},
+		},
+		{
+			name:   "Last page",
+			offset: Offset{Limit: 10, Offset: 20},
+			total:  25,
+			wantLinks: response.PageLinks{
+				First: params("10", "0"),
+				Prev:  params("10", "10"),
+				Last:  params("10", "20"),
+			},
+		},
+		{
+			name:   "No items",
+			offset: Offset{Limit: 10},
+			total:  0,
+			wantLinks: response.PageLinks{
+				First: params("10", "0"),
+				Last:  params("10", "0"),
+			},
+		},
+		{
+			name:   "Unknown total",
+			offset: Offset{Limit: 10, Offset: 10},
+			total:  -1,
+			wantLinks: response.PageLinks{
+				First: params("10", "0"),
+				Prev:  params("10", "0"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.offset.Page([]string{}, tt.total)
+			assert.Equal(t, []string{}, got.Items)
+			if tt.total >= 0 {
+				assert.Equal(t, tt.total, *got.Total)
+			} else {
+				assert.Nil(t, got.Total)
+			}
+			assert.Equal(t, tt.wantLinks, got.Links)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package query
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+var timeType = reflect.TypeOf(time.Time{})
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Decode populates the fields of dst, which must be a pointer to a struct, from the values using the names in the
+// given struct tag (e.g. `query:"limit"`). Fields without the tag are ignored, and fields of embedded structs are
+// decoded as if they were fields of dst.
+//
+// Supported field types are strings, booleans, integers, floats, time.Duration, time.Time (RFC 3339), types
+// implementing encoding.TextUnmarshaler, and pointers or slices of these. Slices are populated from repeated values.
+//
+// Errors are returned keyed by the tag name of the field that could not be decoded.
+func Decode(values url.Values, dst any, tag string) map[string]error {
+	errs := map[string]error{}
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		errs[""] = fmt.Errorf("decode destination must be a pointer to a struct, got %T", dst)
+		return errs
+	}
+	decodeStruct(values, rv.Elem(), tag, errs)
+	return errs
+}
+
+func decodeStruct(values url.Values, rv reflect.Value, tag string, errs map[string]error) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			decodeStruct(values, rv.Field(i), tag, errs)
+			continue
+		}
+		name := TagName(field, tag)
+		if name == "" || !field.IsExported() {
+			continue
+		}
+		v, ok := values[name]
+		if !ok || len(v) == 0 {
+			continue
+		}
+		if err := DecodeValue(rv.Field(i), v); err != nil {
+			errs[name] = err
+		}
+	}
+}
+
+// TagName returns the name of a struct field in the given tag, ignoring any options after a comma. An empty string is
+// returned if the field does not have the tag, or the tag name is "-".
+func TagName(field reflect.StructField, tag string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// DecodeValue sets the value of a field from one or more string values. Slices are populated from all the values,
+// otherwise the first value is used.
+func DecodeValue(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !field.Type().Implements(textUnmarshalerType) &&
+		field.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := decodeString(slice.Index(i), v); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return decodeString(field, values[0])
+}
+
+func decodeString(field reflect.Value, s string) error {
+	if field.Kind() == reflect.Pointer {
+		v := reflect.New(field.Type().Elem())
+		if err := decodeString(v.Elem(), s); err != nil {
+			return err
+		}
+		field.Set(v)
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		if err := field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("invalid value %q: %w", s, err)
+		}
+		return nil
+	}
+
+	switch field.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		field.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("invalid time %q, expected RFC 3339 format", s)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package query
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type decodeEmbedded struct {
+	Page int `query:"page"`
+}
+
+type decodeTarget struct {
+	decodeEmbedded
+	Name     string        `query:"name"`
+	Active   bool          `query:"active"`
+	Limit    int           `query:"limit"`
+	Small    int8          `query:"small"`
+	Count    uint          `query:"count"`
+	Ratio    float64       `query:"ratio"`
+	Timeout  time.Duration `query:"timeout"`
+	Since    time.Time     `query:"since"`
+	Id       uuid.UUID     `query:"id"`
+	Optional *string       `query:"optional"`
+	Tags     []string      `query:"tag"`
+	Ids      []int         `query:"ids,omitempty"`
+	Ignored  string        `query:"-"`
+	Untagged string
+}
+
+func TestDecode(t *testing.T) {
+	optional := "test"
+	tests := []struct {
+		name     string
+		values   url.Values
+		want     decodeTarget
+		wantErrs []string
+	}{
+		{
+			name:   "No values leaves zero values",
+			values: url.Values{},
+			want:   decodeTarget{},
+		},
+		{
+			name: "All supported types are decoded",
+			values: url.Values{
+				"page":     {"2"},
+				"name":     {"test"},
+				"active":   {"true"},
+				"limit":    {"-10"},
+				"small":    {"8"},
+				"count":    {"5"},
+				"ratio":    {"0.5"},
+				"timeout":  {"1m30s"},
+				"since":    {"2024-03-01T12:00:00Z"},
+				"id":       {"6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+				"optional": {"test"},
+				"tag":      {"a", "b"},
+				"ids":      {"1", "2"},
+				"Ignored":  {"test"},
+				"Untagged": {"test"},
+			},
+			want: decodeTarget{
+				decodeEmbedded: decodeEmbedded{Page: 2},
+				Name:           "test",
+				Active:         true,
+				Limit:          -10,
+				Small:          8,
+				Count:          5,
+				Ratio:          0.5,
+				Timeout:        90 * time.Second,
+				Since:          time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+				Id:             uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+				Optional:       &optional,
+				Tags:           []string{"a", "b"},
+				Ids:            []int{1, 2},
+			},
+		},
+		{
+			name: "Invalid values return errors by tag name",
+			values: url.Values{
+				"page":    {"one"},
+				"active":  {"yes please"},
+				"small":   {"1000"},
+				"count":   {"-1"},
+				"ratio":   {"half"},
+				"timeout": {"soon"},
+				"since":   {"yesterday"},
+				"id":      {"not-a-uuid"},
+				"ids":     {"1", "two"},
+				"name":    {"test"},
+			},
+			want: decodeTarget{
+				Name: "test",
+			},
+			wantErrs: []string{"page", "active", "small", "count", "ratio", "timeout", "since", "id", "ids"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got decodeTarget
+			errs := Decode(tt.values, &got, "query")
+
+			gotErrs := make([]string, 0, len(errs))
+			for name := range errs {
+				gotErrs = append(gotErrs, name)
+			}
+			assert.ElementsMatchf(t, tt.wantErrs, gotErrs, "Decode() errors")
+			assert.Equalf(t, tt.want, got, "Decode()")
+		})
+	}
+}
+
+func TestDecode_InvalidDestination(t *testing.T) {
+	var s decodeTarget
+	for _, dst := range []any{nil, s, new(string), (*decodeTarget)(nil)} {
+		errs := Decode(url.Values{}, dst, "query")
+		assert.Containsf(t, errs, "", "Decode(%T)", dst)
+	}
+}
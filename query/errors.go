@@ -0,0 +1,43 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+// ErrorDetails converts the errors returned by the validator into a 400 Bad Request error. The meta of the error holds
+// a message for each invalid parameter.
+func ErrorDetails(errs map[string]error) response.ErrorDetails {
+	return ErrorDetailsWithStatus(http.StatusBadRequest, errs)
+}
+
+// ErrorDetailsWithStatus converts the errors returned by the validator into an error with the given status code. The
+// meta of the error holds a message for each invalid parameter.
+func ErrorDetailsWithStatus(statusCode int, errs map[string]error) response.ErrorDetails {
+	meta := map[string]string{}
+	for param, err := range errs {
+		meta[param] = ErrorMessage(err)
+	}
+	return response.NewError(statusCode).WithMeta(meta)
+}
+
+// ErrorMessage returns a human-readable message for an error returned by the validator.
+func ErrorMessage(err error) string {
+	var fe validator.FieldError
+	var ve validator.ValidationErrors
+	switch {
+	case errors.As(err, &fe):
+	case errors.As(err, &ve) && len(ve) > 0:
+		fe = ve[0]
+	default:
+		return err.Error()
+	}
+
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed on the '%s=%s' rule", fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("failed on the '%s' rule", fe.Tag())
+}
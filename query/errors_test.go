@@ -0,0 +1,31 @@
+package query
+
+import (
+	"errors"
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestErrorDetails(t *testing.T) {
+	v := NewValidator()
+	errs := map[string]error{
+		"limit":  v.validate.Var("1000", "max=3"),
+		"status": v.validate.Var("", "required"),
+		"page":   errors.New(`invalid integer "one"`),
+	}
+
+	want := response.NewError(http.StatusBadRequest).WithMeta(map[string]string{
+		"limit":  "failed on the 'max=3' rule",
+		"status": "failed on the 'required' rule",
+		"page":   `invalid integer "one"`,
+	})
+	assert.Equal(t, want, ErrorDetails(errs))
+}
+
+func TestErrorDetailsWithStatus(t *testing.T) {
+	got := ErrorDetailsWithStatus(http.StatusUnprocessableEntity, map[string]error{"name": errors.New("test")})
+	assert.Equal(t, http.StatusUnprocessableEntity, got.Status)
+	assert.Equal(t, map[string]string{"name": "test"}, got.Meta)
+}
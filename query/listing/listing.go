@@ -0,0 +1,227 @@
+package listing
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ellogroup/ello-golang-http/query"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var validate = query.NewValidator()
+
+// FieldType is the type of the values of a field, used to decode filter values.
+type FieldType int
+
+const (
+	String FieldType = iota
+	Int
+	Float
+	Bool
+	Time
+)
+
+var fieldTypes = map[FieldType]reflect.Type{
+	String: reflect.TypeOf(""),
+	Int:    reflect.TypeOf(0),
+	Float:  reflect.TypeOf(float64(0)),
+	Bool:   reflect.TypeOf(false),
+	Time:   reflect.TypeOf(time.Time{}),
+}
+
+// Operator is a filter comparison.
+type Operator string
+
+const (
+	Eq       Operator = "eq"
+	Ne       Operator = "ne"
+	Gt       Operator = "gt"
+	Lt       Operator = "lt"
+	In       Operator = "in"
+	Contains Operator = "contains"
+)
+
+// Field declares a field that can be used to sort or filter a list.
+type Field struct {
+	// Type is the type of the filter values. Time values must be in RFC 3339 format.
+	Type FieldType
+	// Sortable reports whether the list can be sorted by the field.
+	Sortable bool
+	// Operators are the filter operators allowed for the field. The field can not be filtered if empty.
+	Operators []Operator
+	// Rules are the validation rules applied to each filter value, e.g. "oneof=active inactive".
+	Rules string
+}
+
+// Schema is the fields of a list that can be sorted or filtered, keyed by the name used in the query.
+type Schema map[string]Field
+
+// Sort is a field to sort by.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Filter is a comparison of a field with one or more values. Values are decoded to the type of the field: string,
+// int, float64, bool or time.Time. Only the In operator has more than one value.
+type Filter struct {
+	Field    string
+	Operator Operator
+	Values   []any
+}
+
+// Listing is the sort and filters parsed from a query.
+type Listing struct {
+	Sort    []Sort
+	Filters []Filter
+}
+
+// Parse parses the sort and filters from the query. See ParseSort and ParseFilters.
+func (s Schema) Parse(q url.Values) (Listing, error) {
+	errs := map[string]error{}
+	l := Listing{
+		Sort:    s.parseSort(q, errs),
+		Filters: s.parseFilters(q, errs),
+	}
+	if len(errs) > 0 {
+		return Listing{}, query.ErrorDetails(errs)
+	}
+	return l, nil
+}
+
+// ParseSort parses the `sort` query parameter, a comma-separated list of fields in order of precedence. Fields are
+// sorted ascending, or descending if prefixed with "-", e.g. `sort=-created_at,name`.
+//
+// The returned error will be a 400 Bad Request response.ErrorDetails if a field is not sortable.
+func (s Schema) ParseSort(q url.Values) ([]Sort, error) {
+	errs := map[string]error{}
+	sorts := s.parseSort(q, errs)
+	if len(errs) > 0 {
+		return nil, query.ErrorDetails(errs)
+	}
+	return sorts, nil
+}
+
+// ParseFilters parses the `filter` query parameters, in the form `filter[field]=value` for equality or
+// `filter[field][operator]=value` for any other operator. Values of the In operator are comma-separated, e.g.
+// `filter[status][in]=active,pending`. Filters are returned sorted by field and operator.
+//
+// The returned error will be a 400 Bad Request response.ErrorDetails if a field or operator is not allowed, or a value
+// is invalid, with the details of each invalid parameter in the meta.
+func (s Schema) ParseFilters(q url.Values) ([]Filter, error) {
+	errs := map[string]error{}
+	filters := s.parseFilters(q, errs)
+	if len(errs) > 0 {
+		return nil, query.ErrorDetails(errs)
+	}
+	return filters, nil
+}
+
+func (s Schema) parseSort(q url.Values, errs map[string]error) []Sort {
+	param := q.Get("sort")
+	if param == "" {
+		return nil
+	}
+
+	var sorts []Sort
+	for _, name := range strings.Split(param, ",") {
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimLeft(name, "+-")
+		if field, ok := s[name]; !ok || !field.Sortable {
+			errs["sort"] = fmt.Errorf("cannot sort by '%s'", name)
+			return nil
+		}
+		if slices.ContainsFunc(sorts, func(sort Sort) bool { return sort.Field == name }) {
+			errs["sort"] = fmt.Errorf("cannot sort by '%s' more than once", name)
+			return nil
+		}
+		sorts = append(sorts, Sort{Field: name, Desc: desc})
+	}
+	return sorts
+}
+
+func (s Schema) parseFilters(q url.Values, errs map[string]error) []Filter {
+	var filters []Filter
+	for param, values := range q {
+		if !strings.HasPrefix(param, "filter[") {
+			continue
+		}
+		name, op, ok := parseFilterParam(param)
+		if !ok {
+			errs[param] = errors.New("invalid filter, expected filter[field] or filter[field][operator]")
+			continue
+		}
+		field, exists := s[name]
+		if !exists || len(field.Operators) == 0 {
+			errs[param] = fmt.Errorf("cannot filter by '%s'", name)
+			continue
+		}
+		if !slices.Contains(field.Operators, op) {
+			errs[param] = fmt.Errorf("operator '%s' is not allowed", op)
+			continue
+		}
+
+		raw := values[len(values)-1:]
+		if op == In {
+			raw = strings.Split(values[len(values)-1], ",")
+		}
+		filter := Filter{Field: name, Operator: op}
+		for _, v := range raw {
+			value, err := decodeValue(field, v)
+			if err != nil {
+				errs[param] = err
+				break
+			}
+			filter.Values = append(filter.Values, value)
+		}
+		if _, invalid := errs[param]; !invalid {
+			filters = append(filters, filter)
+		}
+	}
+
+	sort.Slice(filters, func(i, j int) bool {
+		if filters[i].Field != filters[j].Field {
+			return filters[i].Field < filters[j].Field
+		}
+		return filters[i].Operator < filters[j].Operator
+	})
+	return filters
+}
+
+// parseFilterParam parses the field and operator from a filter query parameter, e.g. `filter[created_at][gt]`.
+func parseFilterParam(param string) (string, Operator, bool) {
+	rest := strings.TrimPrefix(param, "filter[")
+	name, rest, ok := strings.Cut(rest, "]")
+	if !ok || name == "" {
+		return "", "", false
+	}
+	if rest == "" {
+		return name, Eq, true
+	}
+	op, ok := strings.CutPrefix(rest, "[")
+	if !ok || !strings.HasSuffix(op, "]") || strings.Count(op, "]") != 1 {
+		return "", "", false
+	}
+	return name, Operator(strings.TrimSuffix(op, "]")), true
+}
+
+func decodeValue(field Field, s string) (any, error) {
+	rt, ok := fieldTypes[field.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported field type %d", field.Type)
+	}
+	v := reflect.New(rt).Elem()
+	if err := query.DecodeValue(v, []string{s}); err != nil {
+		return nil, err
+	}
+	if field.Rules != "" {
+		if err := validate.ValidateVar(v.Interface(), field.Rules); err != nil {
+			return nil, err
+		}
+	}
+	return v.Interface(), nil
+}
@@ -0,0 +1,123 @@
+package listing
+
+import (
+	"github.com/ellogroup/ello-golang-http/response"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var schema = Schema{
+	"name":       {Type: String, Sortable: true, Operators: []Operator{Eq, Contains}},
+	"status":     {Type: String, Operators: []Operator{Eq, Ne, In}, Rules: "oneof=active pending closed"},
+	"amount":     {Type: Float, Sortable: true, Operators: []Operator{Gt, Lt}},
+	"count":      {Type: Int, Operators: []Operator{Eq, In}},
+	"archived":   {Type: Bool, Operators: []Operator{Eq}},
+	"created_at": {Type: Time, Sortable: true, Operators: []Operator{Gt, Lt}},
+}
+
+func TestSchema_Parse(t *testing.T) {
+	tests := []struct {
+		name     string
+		q        url.Values
+		want     Listing
+		wantMeta map[string]string
+	}{
+		{
+			name: "Empty query",
+			q:    url.Values{"limit": {"10"}},
+			want: Listing{},
+		},
+		{
+			name: "Sort and filters are parsed",
+			q: url.Values{
+				"sort":                   {"-created_at,+name,amount"},
+				"filter[status][in]":     {"active,pending"},
+				"filter[name]":           {"test"},
+				"filter[amount][gt]":     {"9.5"},
+				"filter[count][in]":      {"1,2"},
+				"filter[archived]":       {"false"},
+				"filter[created_at][lt]": {"2024-03-01T12:00:00Z"},
+			},
+			want: Listing{
+				Sort: []Sort{{Field: "created_at", Desc: true}, {Field: "name"}, {Field: "amount"}},
+				Filters: []Filter{
+					{Field: "amount", Operator: Gt, Values: []any{9.5}},
+					{Field: "archived", Operator: Eq, Values: []any{false}},
+					{Field: "count", Operator: In, Values: []any{1, 2}},
+					{Field: "created_at", Operator: Lt, Values: []any{time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}},
+					{Field: "name", Operator: Eq, Values: []any{"test"}},
+					{Field: "status", Operator: In, Values: []any{"active", "pending"}},
+				},
+			},
+		},
+		{
+			name: "Field is not sortable",
+			q:    url.Values{"sort": {"name,status"}},
+			wantMeta: map[string]string{
+				"sort": "cannot sort by 'status'",
+			},
+		},
+		{
+			name: "Field is sorted more than once",
+			q:    url.Values{"sort": {"name,-name"}},
+			wantMeta: map[string]string{
+				"sort": "cannot sort by 'name' more than once",
+			},
+		},
+		{
+			name: "Filter errors are keyed by parameter",
+			q: url.Values{
+				"filter[unknown]":      {"1"},
+				"filter[name][gt]":     {"a"},
+				"filter[status]":       {"deleted"},
+				"filter[count][in]":    {"1,two"},
+				"filter[amount][gt]":   {"lots"},
+				"filter[created_at":    {"2024"},
+				"filter[name][eq][eq]": {"a"},
+			},
+			wantMeta: map[string]string{
+				"filter[unknown]":      "cannot filter by 'unknown'",
+				"filter[name][gt]":     "operator 'gt' is not allowed",
+				"filter[status]":       "failed on the 'oneof=active pending closed' rule",
+				"filter[count][in]":    `invalid integer "two"`,
+				"filter[amount][gt]":   `invalid number "lots"`,
+				"filter[created_at":    "invalid filter, expected filter[field] or filter[field][operator]",
+				"filter[name][eq][eq]": "invalid filter, expected filter[field] or filter[field][operator]",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.Parse(tt.q)
+			if tt.wantMeta == nil {
+				assert.NoError(t, err)
+				assert.Equalf(t, tt.want, got, "Parse()")
+				return
+			}
+			details := response.AsErrorDetails(err)
+			assert.Equal(t, http.StatusBadRequest, details.Status)
+			assert.Equal(t, tt.wantMeta, details.Meta)
+		})
+	}
+}
+
+func TestSchema_ParseSort(t *testing.T) {
+	got, err := schema.ParseSort(url.Values{"sort": {"-amount"}, "filter[unknown]": {"1"}})
+	assert.NoError(t, err, "filters are not parsed")
+	assert.Equal(t, []Sort{{Field: "amount", Desc: true}}, got)
+
+	_, err = schema.ParseSort(url.Values{"sort": {"unknown"}})
+	assert.Equal(t, http.StatusBadRequest, response.AsErrorDetails(err).Status)
+}
+
+func TestSchema_ParseFilters(t *testing.T) {
+	got, err := schema.ParseFilters(url.Values{"sort": {"unknown"}, "filter[status][ne]": {"closed"}})
+	assert.NoError(t, err, "sort is not parsed")
+	assert.Equal(t, []Filter{{Field: "status", Operator: Ne, Values: []any{"closed"}}}, got)
+
+	_, err = schema.ParseFilters(url.Values{"filter[unknown]": {"1"}})
+	assert.Equal(t, http.StatusBadRequest, response.AsErrorDetails(err).Status)
+}
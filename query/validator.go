@@ -1,20 +1,29 @@
 package query
 
 import (
+	"errors"
 	"github.com/go-playground/validator/v10"
 	"net/url"
+	"reflect"
+	"strings"
 )
 
 type Var interface {
 	Var(field interface{}, tag string) error
 }
 
+type Struct interface {
+	Struct(s interface{}) error
+}
+
 type Validator struct {
-	validate Var
+	validate       Var
+	validateStruct Struct
 }
 
 func NewValidator() *Validator {
-	return &Validator{validate: validator.New()}
+	v := validator.New()
+	return &Validator{validate: v, validateStruct: v}
 }
 
 func (v Validator) Validate(q url.Values, rules map[string]string) map[string]error {
@@ -26,3 +35,80 @@ func (v Validator) Validate(q url.Values, rules map[string]string) map[string]er
 	}
 	return e
 }
+
+// ValidateVar validates a single value against the validation rules, e.g. "min=1,max=100".
+func (v Validator) ValidateVar(value any, rules string) error {
+	return v.validate.Var(value, rules)
+}
+
+// Bind decodes the query into dst, which must be a pointer to a struct, using `query` struct tags, and then validates
+// dst using `validate` struct tags. Errors are returned keyed by query parameter.
+func (v Validator) Bind(q url.Values, dst any) map[string]error {
+	return v.BindTag(q, dst, "query")
+}
+
+// BindTag decodes the values into dst, which must be a pointer to a struct, using the given struct tag, and then
+// validates dst using `validate` struct tags. Errors are returned keyed by the tag name of the field. Fields that could
+// not be decoded are not also reported as failing validation.
+func (v Validator) BindTag(values url.Values, dst any, tag string) map[string]error {
+	e := Decode(values, dst, tag)
+	if _, ok := e[""]; ok {
+		return e
+	}
+	for name, err := range v.ValidateStruct(dst, tag) {
+		if _, exists := e[name]; !exists {
+			e[name] = err
+		}
+	}
+	return e
+}
+
+// ValidateStruct validates s, which must be a struct or a pointer to a struct, using `validate` struct tags. Errors are
+// returned keyed by the name of the field in the given struct tag, or by the field name if it does not have the tag.
+func (v Validator) ValidateStruct(s any, tag string) map[string]error {
+	e := map[string]error{}
+	if v.validateStruct == nil {
+		return e
+	}
+
+	var ve validator.ValidationErrors
+	if err := v.validateStruct.Struct(s); errors.As(err, &ve) {
+		rt := reflect.TypeOf(s)
+		if rt.Kind() == reflect.Pointer {
+			rt = rt.Elem()
+		}
+		names := fieldNames(rt, tag, "")
+		for _, fe := range ve {
+			// Strip the struct name from the namespace
+			_, ns, _ := strings.Cut(fe.StructNamespace(), ".")
+			name, ok := names[ns]
+			if !ok {
+				name = ns
+			}
+			if _, exists := e[name]; !exists {
+				e[name] = fe
+			}
+		}
+	} else if err != nil {
+		e[""] = err
+	}
+	return e
+}
+
+// fieldNames maps the namespace of each tagged struct field to its tag name, including fields of embedded structs.
+func fieldNames(rt reflect.Type, tag, prefix string) map[string]string {
+	names := map[string]string{}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for ns, name := range fieldNames(field.Type, tag, prefix+field.Name+".") {
+				names[ns] = name
+			}
+			continue
+		}
+		if name := TagName(field, tag); name != "" {
+			names[prefix+field.Name] = name
+		}
+	}
+	return names
+}
@@ -115,3 +115,84 @@ func TestValidator_Validate(t *testing.T) {
 		})
 	}
 }
+
+type bindTarget struct {
+	decodeEmbedded
+	Limit  int    `query:"limit" form:"max" validate:"min=1,max=100"`
+	Status string `query:"status" validate:"required,oneof=active inactive"`
+}
+
+func TestValidator_Bind(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   url.Values
+		want     bindTarget
+		wantErrs map[string]string
+	}{
+		{
+			name:   "Valid values are decoded",
+			values: url.Values{"limit": {"10"}, "status": {"active"}, "page": {"2"}},
+			want:   bindTarget{decodeEmbedded: decodeEmbedded{Page: 2}, Limit: 10, Status: "active"},
+		},
+		{
+			name:     "Invalid values are keyed by query parameter",
+			values:   url.Values{"limit": {"1000"}},
+			want:     bindTarget{Limit: 1000},
+			wantErrs: map[string]string{"limit": "max", "status": "required"},
+		},
+		{
+			name:     "Decode errors are not also reported as validation errors",
+			values:   url.Values{"limit": {"ten"}, "status": {"active"}},
+			want:     bindTarget{Status: "active"},
+			wantErrs: map[string]string{"limit": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bindTarget
+			errs := NewValidator().Bind(tt.values, &got)
+
+			assert.Equalf(t, tt.want, got, "Bind()")
+			assert.Lenf(t, errs, len(tt.wantErrs), "Bind() errors")
+			for param, rule := range tt.wantErrs {
+				if !assert.Containsf(t, errs, param, "Bind() errors") || rule == "" {
+					continue
+				}
+				assert.Containsf(t, ErrorMessage(errs[param]), "'"+rule, "Bind() errors[%v]", param)
+			}
+		})
+	}
+}
+
+func TestValidator_BindTag(t *testing.T) {
+	var got bindTarget
+	errs := NewValidator().BindTag(url.Values{"max": {"0"}}, &got, "form")
+
+	assert.Contains(t, errs, "max")
+	assert.Contains(t, errs, "Status", "untagged fields are keyed by field name")
+}
+
+func TestValidator_ValidateStruct(t *testing.T) {
+	v := NewValidator()
+
+	errs := v.ValidateStruct(bindTarget{Limit: 0, Status: "active"}, "query")
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs, "limit", "struct values are validated")
+
+	errs = v.ValidateStruct(&bindTarget{Limit: 10, Status: "active"}, "query")
+	assert.Empty(t, errs)
+
+	errs = v.ValidateStruct("not a struct", "query")
+	assert.Contains(t, errs, "", "invalid values are reported under an empty key")
+}
+
+func TestValidator_ValidateVar(t *testing.T) {
+	m := new(validateMock)
+	m.On("Var", 10, "min=1").Return(nil)
+	m.On("Var", 0, "min=1").Return(errors.New("min"))
+
+	v := Validator{validate: m}
+	assert.NoError(t, v.ValidateVar(10, "min=1"))
+	assert.Error(t, v.ValidateVar(0, "min=1"))
+	m.AssertExpectations(t)
+}
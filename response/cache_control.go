@@ -0,0 +1,315 @@
+package response
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxDeltaSeconds = 1 << 31
+
+// knownDirectives are the directives with fields of CacheControl.
+var knownDirectives = []string{
+	"public", "private", "no-cache", "no-store", "no-transform", "must-revalidate", "proxy-revalidate",
+	"must-understand", "immutable", "only-if-cached", "max-age", "s-maxage", "max-stale", "min-fresh",
+	"stale-while-revalidate", "stale-if-error",
+}
+
+// CacheControl holds the directives of a Cache-Control header, as defined in RFC 9111 section 5.2, along with the
+// stale-while-revalidate and stale-if-error (RFC 5861) and immutable (RFC 8246) extensions. Durations are written in
+// whole seconds, and are omitted when zero unless parsed from a header or marked with Set, e.g. to write `max-age=0`.
+// To require a cache to revalidate a response before reuse, use NoCache.
+type CacheControl struct {
+	Public          bool
+	Private         bool
+	PrivateFields   []string
+	NoCache         bool
+	NoCacheFields   []string
+	NoStore         bool
+	NoTransform     bool
+	MustRevalidate  bool
+	ProxyRevalidate bool
+	MustUnderstand  bool
+	Immutable       bool
+	OnlyIfCached    bool
+
+	MaxAge               time.Duration
+	SharedMaxAge         time.Duration
+	MaxStale             time.Duration
+	MinFresh             time.Duration
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+
+	// MaxStaleAny is set by max-stale without a value, accepting a stale response of any age. It takes precedence over
+	// MaxStale.
+	MaxStaleAny bool
+
+	// Extensions holds any other directives, keyed by lowercase directive name. Directives without a value have an
+	// empty string value.
+	Extensions map[string]string
+
+	// present holds the names of the directives found when parsed from a header, or marked with Set.
+	present map[string]bool
+}
+
+// ParseCacheControl parses the directives of a Cache-Control (or Surrogate-Control) header. Directive names are case
+// insensitive, and invalid durations are ignored.
+func ParseCacheControl(header string) CacheControl {
+	cc := CacheControl{present: map[string]bool{}}
+	for _, part := range splitDirectives(header) {
+		name, value, _ := strings.Cut(part, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if name == "" {
+			continue
+		}
+		cc.present[name] = true
+
+		switch name {
+		case "public":
+			cc.Public = true
+		case "private":
+			cc.Private = true
+			cc.PrivateFields = splitFields(value)
+		case "no-cache":
+			cc.NoCache = true
+			cc.NoCacheFields = splitFields(value)
+		case "no-store":
+			cc.NoStore = true
+		case "no-transform":
+			cc.NoTransform = true
+		case "must-revalidate":
+			cc.MustRevalidate = true
+		case "proxy-revalidate":
+			cc.ProxyRevalidate = true
+		case "must-understand":
+			cc.MustUnderstand = true
+		case "immutable":
+			cc.Immutable = true
+		case "only-if-cached":
+			cc.OnlyIfCached = true
+		case "max-age":
+			cc.MaxAge = cc.parseDuration(name, value)
+		case "s-maxage":
+			cc.SharedMaxAge = cc.parseDuration(name, value)
+		case "max-stale":
+			// Without a value, any staleness is acceptable (RFC 9111 section 5.2.1.2)
+			if value == "" {
+				cc.MaxStaleAny = true
+				continue
+			}
+			cc.MaxStale = cc.parseDuration(name, value)
+		case "min-fresh":
+			cc.MinFresh = cc.parseDuration(name, value)
+		case "stale-while-revalidate":
+			cc.StaleWhileRevalidate = cc.parseDuration(name, value)
+		case "stale-if-error":
+			cc.StaleIfError = cc.parseDuration(name, value)
+		default:
+			if cc.Extensions == nil {
+				cc.Extensions = map[string]string{}
+			}
+			cc.Extensions[name] = value
+		}
+	}
+	return cc
+}
+
+// Set returns a copy of the directives with the given directives marked as present, so they are written even when
+// their value is zero, e.g. CacheControl{}.Set("max-age") for `max-age=0`. Unknown directives are added as
+// extensions without a value.
+func (cc CacheControl) Set(directives ...string) CacheControl {
+	cc.present = maps.Clone(cc.present)
+	if cc.present == nil {
+		cc.present = map[string]bool{}
+	}
+	for _, d := range directives {
+		d = strings.ToLower(d)
+		cc.present[d] = true
+		if _, ok := cc.Extensions[d]; !ok && !slices.Contains(knownDirectives, d) {
+			cc.Extensions = maps.Clone(cc.Extensions)
+			if cc.Extensions == nil {
+				cc.Extensions = map[string]string{}
+			}
+			cc.Extensions[d] = ""
+		}
+	}
+	return cc
+}
+
+// Has reports whether the directive was present when parsed from a header, marked with Set, or is set to a non-zero
+// value.
+func (cc CacheControl) Has(directive string) bool {
+	directive = strings.ToLower(directive)
+	if cc.present[directive] {
+		return true
+	}
+	for _, d := range cc.directives() {
+		if name, _, _ := strings.Cut(d, "="); name == directive {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the directives formatted as a Cache-Control header value.
+func (cc CacheControl) String() string {
+	return strings.Join(cc.directives(), ", ")
+}
+
+func (cc CacheControl) directives() []string {
+	var d []string
+	flag := func(set bool, name string, fields []string) {
+		switch {
+		case !set && !cc.present[name]:
+		case len(fields) > 0:
+			d = append(d, name+`="`+strings.Join(fields, ", ")+`"`)
+		default:
+			d = append(d, name)
+		}
+	}
+	duration := func(v time.Duration, name string) {
+		if v > 0 || cc.present[name] {
+			d = append(d, name+"="+strconv.FormatInt(int64(v/time.Second), 10))
+		}
+	}
+
+	flag(cc.Public, "public", nil)
+	flag(cc.Private, "private", cc.PrivateFields)
+	flag(cc.NoCache, "no-cache", cc.NoCacheFields)
+	flag(cc.NoStore, "no-store", nil)
+	flag(cc.NoTransform, "no-transform", nil)
+	flag(cc.MustRevalidate, "must-revalidate", nil)
+	flag(cc.ProxyRevalidate, "proxy-revalidate", nil)
+	flag(cc.MustUnderstand, "must-understand", nil)
+	flag(cc.Immutable, "immutable", nil)
+	flag(cc.OnlyIfCached, "only-if-cached", nil)
+	duration(cc.MaxAge, "max-age")
+	duration(cc.SharedMaxAge, "s-maxage")
+	if cc.MaxStaleAny {
+		d = append(d, "max-stale")
+	} else {
+		duration(cc.MaxStale, "max-stale")
+	}
+	duration(cc.MinFresh, "min-fresh")
+	duration(cc.StaleWhileRevalidate, "stale-while-revalidate")
+	duration(cc.StaleIfError, "stale-if-error")
+	extensions := make([]string, 0, len(cc.Extensions))
+	for name := range cc.Extensions {
+		extensions = append(extensions, name)
+	}
+	sort.Strings(extensions)
+	for _, name := range extensions {
+		value := cc.Extensions[name]
+		if value == "" {
+			d = append(d, name)
+			continue
+		}
+		d = append(d, name+"="+value)
+	}
+	return d
+}
+
+// WithCacheControl sets the Cache-Control header of the response.
+func (r Response) WithCacheControl(cc CacheControl) Response {
+	c := r.Clone()
+	c.Headers.Set("Cache-Control", cc.String())
+	return c
+}
+
+// CacheControl returns the parsed directives of the Cache-Control header of the response.
+func (r Response) CacheControl() CacheControl {
+	return ParseCacheControl(strings.Join(r.Headers.Values("Cache-Control"), ", "))
+}
+
+// WithSurrogateControl sets the Surrogate-Control header of the response, used to control caching by CDNs separately
+// from the Cache-Control header.
+func (r Response) WithSurrogateControl(cc CacheControl) Response {
+	c := r.Clone()
+	c.Headers.Set("Surrogate-Control", cc.String())
+	return c
+}
+
+// WithVary adds request headers to the Vary header of the response.
+func (r Response) WithVary(headers ...string) Response {
+	c := r.Clone()
+	vary := splitFields(strings.Join(c.Headers.Values("Vary"), ", "))
+	for _, h := range headers {
+		h = http.CanonicalHeaderKey(h)
+		if !containsFold(vary, h) {
+			vary = append(vary, h)
+		}
+	}
+	c.Headers.Set("Vary", strings.Join(vary, ", "))
+	return c
+}
+
+// WithExpires sets the Expires header of the response.
+func (r Response) WithExpires(t time.Time) Response {
+	c := r.Clone()
+	c.Headers.Set("Expires", t.UTC().Format(http.TimeFormat))
+	return c
+}
+
+// splitDirectives splits a header on commas, ignoring commas within quoted strings.
+func splitDirectives(header string) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i, ch := range header {
+		switch {
+		case ch == '"':
+			quoted = !quoted
+		case ch == ',' && !quoted:
+			parts = append(parts, strings.TrimSpace(header[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(header[start:]))
+}
+
+func splitFields(value string) []string {
+	var fields []string
+	for _, f := range strings.Split(value, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// parseDuration parses the value of a duration directive. Invalid values are ignored, so the directive is not present.
+func (cc CacheControl) parseDuration(name, value string) time.Duration {
+	d, ok := parseSeconds(value)
+	if !ok {
+		delete(cc.present, name)
+	}
+	return d
+}
+
+// parseSeconds parses a delta-seconds value, returning false if invalid. Values too large to be represented are capped
+// at 2^31 seconds, as described in RFC 9111 section 1.2.2.
+func parseSeconds(value string) (time.Duration, bool) {
+	s, err := strconv.ParseInt(value, 10, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) || s < 0 {
+		return 0, false
+	}
+	if s > maxDeltaSeconds {
+		s = maxDeltaSeconds
+	}
+	return time.Duration(s) * time.Second, true
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package response
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCacheControl_String(t *testing.T) {
+	tests := []struct {
+		name string
+		cc   CacheControl
+		want string
+	}{
+		{
+			name: "Empty returns empty string",
+			cc:   CacheControl{},
+			want: "",
+		},
+		{
+			name: "Durations are written in whole seconds",
+			cc: CacheControl{
+				Public:               true,
+				MaxAge:               5 * time.Minute,
+				SharedMaxAge:         time.Hour + 500*time.Millisecond,
+				StaleWhileRevalidate: 30 * time.Second,
+				StaleIfError:         time.Minute,
+			},
+			want: "public, max-age=300, s-maxage=3600, stale-while-revalidate=30, stale-if-error=60",
+		},
+		{
+			name: "Field names are quoted",
+			cc: CacheControl{
+				Private:       true,
+				PrivateFields: []string{"Set-Cookie"},
+				NoCache:       true,
+				NoCacheFields: []string{"X-One", "X-Two"},
+			},
+			want: `private="Set-Cookie", no-cache="X-One, X-Two"`,
+		},
+		{
+			name: "All flags are written",
+			cc: CacheControl{
+				NoStore:         true,
+				NoTransform:     true,
+				MustRevalidate:  true,
+				ProxyRevalidate: true,
+				MustUnderstand:  true,
+				Immutable:       true,
+			},
+			want: "no-store, no-transform, must-revalidate, proxy-revalidate, must-understand, immutable",
+		},
+		{
+			name: "Request directives are written",
+			cc: CacheControl{
+				OnlyIfCached: true,
+				MaxStale:     time.Minute,
+				MinFresh:     10 * time.Second,
+			},
+			want: "only-if-cached, max-stale=60, min-fresh=10",
+		},
+		{
+			name: "max-stale without a value",
+			cc:   CacheControl{MaxStale: time.Minute, MaxStaleAny: true},
+			want: "max-stale",
+		},
+		{
+			name: "Extensions are written in order",
+			cc: CacheControl{
+				Extensions: map[string]string{"zz": "", "aa": "1"},
+			},
+			want: "aa=1, zz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, tt.cc.String(), "String()")
+		})
+	}
+}
+
+func TestParseCacheControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    CacheControl
+		wantHas []string
+	}{
+		{
+			name:   "Empty header",
+			header: "",
+			want:   CacheControl{present: map[string]bool{}},
+		},
+		{
+			name:   "Directives are parsed case insensitively",
+			header: `Public, MAX-AGE=300, s-maxage="600", stale-while-revalidate=30`,
+			want: CacheControl{
+				Public:               true,
+				MaxAge:               5 * time.Minute,
+				SharedMaxAge:         10 * time.Minute,
+				StaleWhileRevalidate: 30 * time.Second,
+				present: map[string]bool{
+					"public": true, "max-age": true, "s-maxage": true, "stale-while-revalidate": true,
+				},
+			},
+			wantHas: []string{"public", "max-age", "s-maxage", "stale-while-revalidate"},
+		},
+		{
+			name:   "Zero max-age is present",
+			header: "max-age=0",
+			want: CacheControl{
+				present: map[string]bool{"max-age": true},
+			},
+			wantHas: []string{"max-age"},
+		},
+		{
+			name:   "max-stale without a value accepts any staleness",
+			header: "max-stale, min-fresh=10",
+			want: CacheControl{
+				MinFresh:    10 * time.Second,
+				MaxStaleAny: true,
+				present:     map[string]bool{"max-stale": true, "min-fresh": true},
+			},
+			wantHas: []string{"max-stale", "min-fresh"},
+		},
+		{
+			name:   "Quoted field names containing commas are parsed",
+			header: `private="Set-Cookie, X-Other", no-cache`,
+			want: CacheControl{
+				Private:       true,
+				PrivateFields: []string{"Set-Cookie", "X-Other"},
+				NoCache:       true,
+				present:       map[string]bool{"private": true, "no-cache": true},
+			},
+		},
+		{
+			name:   "Invalid and overflowing durations",
+			header: "max-age=abc, s-maxage=-1, max-stale=99999999999999999999",
+			want: CacheControl{
+				MaxStale: maxDeltaSeconds * time.Second,
+				present:  map[string]bool{"max-stale": true},
+			},
+		},
+		{
+			name:   "Unknown directives are extensions",
+			header: "no-store, community=UCI, foo",
+			want: CacheControl{
+				NoStore:    true,
+				Extensions: map[string]string{"community": "UCI", "foo": ""},
+				present:    map[string]bool{"no-store": true, "community": true, "foo": true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseCacheControl(tt.header)
+			assert.Equalf(t, tt.want, got, "ParseCacheControl(%v)", tt.header)
+			for _, d := range tt.wantHas {
+				assert.Truef(t, got.Has(d), "Has(%v)", d)
+			}
+		})
+	}
+}
+
+func TestCacheControl_RoundTrip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "max-age=0", want: "max-age=0"},
+		{header: "public, s-maxage=0, max-age=0, must-revalidate", want: "public, must-revalidate, max-age=0, s-maxage=0"},
+		{header: `private="Set-Cookie", stale-if-error=0`, want: `private="Set-Cookie", stale-if-error=0`},
+		{header: "max-age=abc, no-store", want: "no-store"},
+		{header: "max-stale, max-stale=0", want: "max-stale"},
+		{header: "max-stale=0", want: "max-stale=0"},
+		{header: "no-cache, foo=bar, baz", want: "no-cache, baz, foo=bar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			cc := ParseCacheControl(tt.header)
+			assert.Equalf(t, tt.want, cc.String(), "ParseCacheControl(%v).String()", tt.header)
+			assert.Equalf(t, cc, ParseCacheControl(cc.String()), "ParseCacheControl(%v)", cc.String())
+		})
+	}
+}
+
+func TestCacheControl_Set(t *testing.T) {
+	cc := CacheControl{NoCache: true}
+	set := cc.Set("Max-Age", "s-maxage", "foo")
+	assert.Equal(t, "no-cache, max-age=0, s-maxage=0, foo", set.String())
+	assert.True(t, set.Has("max-age"))
+	// The original is unchanged
+	assert.Equal(t, "no-cache", cc.String())
+	assert.False(t, cc.Has("max-age"))
+
+	assert.Equal(t, "max-age=60", CacheControl{MaxAge: time.Minute}.Set("max-age").String())
+}
+
+func TestCacheControl_Has(t *testing.T) {
+	cc := CacheControl{MaxAge: time.Minute, NoStore: true}
+	assert.True(t, cc.Has("max-age"))
+	assert.True(t, cc.Has("No-Store"))
+	assert.False(t, cc.Has("s-maxage"))
+}
+
+func TestResponse_WithCacheControl(t *testing.T) {
+	resp := New(http.StatusOK, nil).WithCacheControl(CacheControl{Public: true, MaxAge: time.Minute})
+	assert.Equal(t, "public, max-age=60", resp.Headers.Get("Cache-Control"))
+
+	got := resp.CacheControl()
+	assert.True(t, got.Public)
+	assert.Equal(t, time.Minute, got.MaxAge)
+}
+
+func TestResponse_WithSurrogateControl(t *testing.T) {
+	resp := New(http.StatusOK, nil).WithSurrogateControl(CacheControl{MaxAge: time.Hour})
+	assert.Equal(t, "max-age=3600", resp.Headers.Get("Surrogate-Control"))
+}
+
+func TestResponse_WithVary(t *testing.T) {
+	resp := New(http.StatusOK, nil).
+		WithVary("accept-language").
+		WithVary("Accept-Encoding", "Accept-Language")
+	assert.Equal(t, []string{"Accept-Language, Accept-Encoding"}, resp.Headers.Values("Vary"))
+}
+
+func TestResponse_WithExpires(t *testing.T) {
+	resp := New(http.StatusOK, nil).WithExpires(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, "Fri, 01 Mar 2024 12:00:00 GMT", resp.Headers.Get("Expires"))
+}
@@ -0,0 +1,155 @@
+package response
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// notModifiedHeaders are the headers that are retained when a response is replaced with a 304 Not Modified, as
+// described in RFC 9110 section 15.4.5.
+var notModifiedHeaders = []string{"Cache-Control", "Content-Location", "Date", "ETag", "Expires", "Vary"}
+
+// WithETag sets a strong entity tag on the response. The tag should be the opaque value only, it will be quoted when
+// written to the ETag header.
+func (r Response) WithETag(tag string) Response {
+	c := r.Clone()
+	c.Headers.Set("ETag", `"`+tag+`"`)
+	return c
+}
+
+// WithWeakETag sets a weak entity tag on the response. The tag should be the opaque value only, it will be quoted and
+// prefixed with the weak indicator when written to the ETag header.
+func (r Response) WithWeakETag(tag string) Response {
+	c := r.Clone()
+	c.Headers.Set("ETag", `W/"`+tag+`"`)
+	return c
+}
+
+// WithComputedETag sets a strong entity tag on the response, computed from a hash of the body as it would be written
+// to the response writer. If the body cannot be encoded the response is returned unchanged, as the same error will be
+// returned when the response is written. Bodies streamed from a reader are also returned unchanged.
+func (r Response) WithComputedETag() Response {
+	b, err := r.body()
+	if err != nil {
+		return r
+	}
+	sum := sha256.Sum256(b)
+	return r.WithETag(hex.EncodeToString(sum[:16]))
+}
+
+// WithLastModified sets the Last-Modified header of the response.
+func (r Response) WithLastModified(t time.Time) Response {
+	c := r.Clone()
+	c.Headers.Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+	return c
+}
+
+// EvaluatePreconditions evaluates the conditional headers of the request against the ETag and Last-Modified headers
+// of the response. If the preconditions pass the response is returned unchanged, otherwise a 304 Not Modified or 412
+// Precondition Failed response is returned in its place.
+func (r Response) EvaluatePreconditions(req *http.Request) Response {
+	var lastModified time.Time
+	if lm := r.Headers.Get("Last-Modified"); lm != "" {
+		lastModified, _ = http.ParseTime(lm)
+	}
+	resp, ok := CheckPreconditions(req, r.Headers.Get("ETag"), lastModified)
+	if ok {
+		return r
+	}
+	closeReader(r.BodyReader)
+	if resp.StatusCode == http.StatusNotModified {
+		for _, h := range notModifiedHeaders {
+			for _, v := range r.Headers.Values(h) {
+				resp.Headers.Add(h, v)
+			}
+		}
+	}
+	return resp
+}
+
+// CheckPreconditions evaluates the conditional headers of the request (If-Match, If-Unmodified-Since, If-None-Match and
+// If-Modified-Since) against the current entity tag and last modified time of a resource, following the order of
+// evaluation in RFC 9110 section 13.2.2. The etag must be formatted as it would be in the ETag header, and either
+// validator may be empty if the resource does not have one.
+//
+// If the request should proceed true is returned. Otherwise, the returned response should be written in place of
+// processing the request: either a 304 Not Modified for safe requests, or a 412 Precondition Failed error. This allows
+// handlers to check preconditions against the current state of a resource before applying a write.
+func CheckPreconditions(req *http.Request, etag string, lastModified time.Time) (Response, bool) {
+	safe := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	// Step 1 & 2: If-Match, or If-Unmodified-Since in its absence
+	if im := req.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, etag, false) {
+			return preconditionFailed(), false
+		}
+	} else if ius := req.Header.Get("If-Unmodified-Since"); ius != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && lastModified.Truncate(time.Second).After(t) {
+			return preconditionFailed(), false
+		}
+	}
+
+	// Step 3 & 4: If-None-Match, or If-Modified-Since in its absence
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		if matchETag(inm, etag, true) {
+			if safe {
+				return NewNoContent(http.StatusNotModified), false
+			}
+			return preconditionFailed(), false
+		}
+	} else if ims := req.Header.Get("If-Modified-Since"); ims != "" && safe && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			return NewNoContent(http.StatusNotModified), false
+		}
+	}
+
+	return Response{}, true
+}
+
+func preconditionFailed() Response {
+	return NewError(http.StatusPreconditionFailed).JsonResponse()
+}
+
+// matchETag reports whether the current etag matches any of the entity tags in a conditional header. Weak comparison
+// is used by If-None-Match, strong comparison is used by If-Match.
+func matchETag(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return etag != ""
+	}
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+			continue
+		}
+		if !strings.HasPrefix(candidate, "W/") && !strings.HasPrefix(etag, "W/") && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// body returns the body of the response as it would be written to the response writer.
+func (r Response) body() ([]byte, error) {
+	if r.ContentType == contentTypeJson && r.BodyDecoded != nil {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(r.BodyDecoded); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	if r.BodyReader != nil {
+		return nil, errBodyReader
+	}
+	return r.BodyEncoded, nil
+}
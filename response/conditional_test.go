@@ -0,0 +1,220 @@
+package response
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResponse_WithETag(t *testing.T) {
+	assert.Equal(t, `"abc"`, New(http.StatusOK, nil).WithETag("abc").Headers.Get("ETag"))
+	assert.Equal(t, `W/"abc"`, New(http.StatusOK, nil).WithWeakETag("abc").Headers.Get("ETag"))
+}
+
+func TestResponse_WithComputedETag(t *testing.T) {
+	plain := New(http.StatusOK, []byte("test body")).WithComputedETag()
+	json := NewJson(http.StatusOK, decodedPayload{Example: "test"}).WithComputedETag()
+	jsonSame := NewJson(http.StatusOK, decodedPayload{Example: "test"}).WithComputedETag()
+	jsonDifferent := NewJson(http.StatusOK, decodedPayload{Example: "other"}).WithComputedETag()
+	unencodable := NewJson(http.StatusOK, make(chan int)).WithComputedETag()
+
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, plain.Headers.Get("ETag"))
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, json.Headers.Get("ETag"))
+	assert.Equal(t, json.Headers.Get("ETag"), jsonSame.Headers.Get("ETag"))
+	assert.NotEqual(t, json.Headers.Get("ETag"), jsonDifferent.Headers.Get("ETag"))
+	assert.Empty(t, unencodable.Headers.Get("ETag"))
+}
+
+func TestResponse_WithLastModified(t *testing.T) {
+	lm := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("test", 3600))
+	assert.Equal(t, "Fri, 01 Mar 2024 11:30:00 GMT", New(http.StatusOK, nil).WithLastModified(lm).Headers.Get("Last-Modified"))
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	lastModified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	before := lastModified.Add(-time.Hour).Format(http.TimeFormat)
+	after := lastModified.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name         string
+		method       string
+		header       http.Header
+		etag         string
+		lastModified time.Time
+		wantOk       bool
+		wantStatus   int
+	}{
+		{
+			name:   "No conditional headers proceeds",
+			method: http.MethodGet,
+			header: http.Header{},
+			etag:   `"abc"`,
+			wantOk: true,
+		},
+		{
+			name:       "If-None-Match matching etag on GET returns not modified",
+			method:     http.MethodGet,
+			header:     http.Header{"If-None-Match": {`"xyz", "abc"`}},
+			etag:       `"abc"`,
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "If-None-Match uses weak comparison",
+			method:     http.MethodGet,
+			header:     http.Header{"If-None-Match": {`W/"abc"`}},
+			etag:       `"abc"`,
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:   "If-None-Match not matching etag proceeds",
+			method: http.MethodGet,
+			header: http.Header{"If-None-Match": {`"xyz"`}},
+			etag:   `"abc"`,
+			wantOk: true,
+		},
+		{
+			name:       "If-None-Match matching etag on PUT returns precondition failed",
+			method:     http.MethodPut,
+			header:     http.Header{"If-None-Match": {"*"}},
+			etag:       `"abc"`,
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:   "If-None-Match wildcard without current etag proceeds",
+			method: http.MethodPut,
+			header: http.Header{"If-None-Match": {"*"}},
+			wantOk: true,
+		},
+		{
+			name:   "If-Match matching etag proceeds",
+			method: http.MethodPut,
+			header: http.Header{"If-Match": {`"abc"`}},
+			etag:   `"abc"`,
+			wantOk: true,
+		},
+		{
+			name:       "If-Match not matching etag returns precondition failed",
+			method:     http.MethodPut,
+			header:     http.Header{"If-Match": {`"xyz"`}},
+			etag:       `"abc"`,
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "If-Match uses strong comparison",
+			method:     http.MethodPut,
+			header:     http.Header{"If-Match": {`W/"abc"`}},
+			etag:       `W/"abc"`,
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "If-Match without current etag returns precondition failed",
+			method:     http.MethodPut,
+			header:     http.Header{"If-Match": {"*"}},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:         "If-Unmodified-Since before last modified returns precondition failed",
+			method:       http.MethodPut,
+			header:       http.Header{"If-Unmodified-Since": {before}},
+			lastModified: lastModified,
+			wantStatus:   http.StatusPreconditionFailed,
+		},
+		{
+			name:         "If-Unmodified-Since after last modified proceeds",
+			method:       http.MethodPut,
+			header:       http.Header{"If-Unmodified-Since": {after}},
+			lastModified: lastModified,
+			wantOk:       true,
+		},
+		{
+			name:         "If-Unmodified-Since ignored when If-Match present",
+			method:       http.MethodPut,
+			header:       http.Header{"If-Match": {`"abc"`}, "If-Unmodified-Since": {before}},
+			etag:         `"abc"`,
+			lastModified: lastModified,
+			wantOk:       true,
+		},
+		{
+			name:         "If-Modified-Since after last modified returns not modified",
+			method:       http.MethodGet,
+			header:       http.Header{"If-Modified-Since": {after}},
+			lastModified: lastModified,
+			wantStatus:   http.StatusNotModified,
+		},
+		{
+			name:         "If-Modified-Since equal to last modified returns not modified",
+			method:       http.MethodGet,
+			header:       http.Header{"If-Modified-Since": {lastModified.Format(http.TimeFormat)}},
+			lastModified: lastModified.Add(500 * time.Millisecond),
+			wantStatus:   http.StatusNotModified,
+		},
+		{
+			name:         "If-Modified-Since before last modified proceeds",
+			method:       http.MethodGet,
+			header:       http.Header{"If-Modified-Since": {before}},
+			lastModified: lastModified,
+			wantOk:       true,
+		},
+		{
+			name:         "If-Modified-Since ignored when If-None-Match present",
+			method:       http.MethodGet,
+			header:       http.Header{"If-None-Match": {`"xyz"`}, "If-Modified-Since": {after}},
+			etag:         `"abc"`,
+			lastModified: lastModified,
+			wantOk:       true,
+		},
+		{
+			name:         "If-Modified-Since ignored for unsafe methods",
+			method:       http.MethodPost,
+			header:       http.Header{"If-Modified-Since": {after}},
+			lastModified: lastModified,
+			wantOk:       true,
+		},
+		{
+			name:         "Invalid date is ignored",
+			method:       http.MethodGet,
+			header:       http.Header{"If-Modified-Since": {"yesterday"}},
+			lastModified: lastModified,
+			wantOk:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &http.Request{Method: tt.method, Header: tt.header}
+			got, ok := CheckPreconditions(req, tt.etag, tt.lastModified)
+			assert.Equalf(t, tt.wantOk, ok, "CheckPreconditions() ok")
+			if !tt.wantOk {
+				assert.Equalf(t, tt.wantStatus, got.StatusCode, "CheckPreconditions() status code")
+			}
+		})
+	}
+}
+
+func TestResponse_EvaluatePreconditions(t *testing.T) {
+	resp := NewJson(http.StatusOK, decodedPayload{Example: "test"}).
+		WithETag("abc").
+		WithLastModified(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)).
+		WithHeader("Cache-Control", "max-age=60").
+		WithHeader("X-Other", "other")
+
+	t.Run("Passing preconditions returns response unchanged", func(t *testing.T) {
+		req := &http.Request{Method: http.MethodGet, Header: http.Header{"If-None-Match": {`"xyz"`}}}
+		assert.Equal(t, resp, resp.EvaluatePreconditions(req))
+	})
+
+	t.Run("Not modified retains validator and caching headers only", func(t *testing.T) {
+		req := &http.Request{Method: http.MethodGet, Header: http.Header{"If-None-Match": {`"abc"`}}}
+		got := resp.EvaluatePreconditions(req)
+		assert.Equal(t, http.StatusNotModified, got.StatusCode)
+		assert.Nil(t, got.BodyDecoded)
+		assert.Equal(t, http.Header{"Etag": {`"abc"`}, "Cache-Control": {"max-age=60"}}, got.Headers)
+	})
+
+	t.Run("Failed precondition returns error", func(t *testing.T) {
+		req := &http.Request{Method: http.MethodGet, Header: http.Header{"If-Match": {`"xyz"`}}}
+		got := resp.EvaluatePreconditions(req)
+		assert.Equal(t, http.StatusPreconditionFailed, got.StatusCode)
+		assert.Equal(t, ErrorBody{ErrorDetails: NewError(http.StatusPreconditionFailed)}, got.BodyDecoded)
+	})
+}
@@ -1,6 +1,8 @@
 package response
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 )
@@ -48,6 +50,11 @@ func (e ErrorDetails) JsonResponse() Response {
 	})
 }
 
+// Error implements the error interface, so error details can be returned as an error and later written as a response.
+func (e ErrorDetails) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
 // NewError creates a new JSON response from a status code.
 func NewError(statusCode int) ErrorDetails {
 	return ErrorDetails{
@@ -61,3 +68,13 @@ func NewError(statusCode int) ErrorDetails {
 func slugify(s string) string {
 	return strings.ToLower(strings.ReplaceAll(s, " ", "_"))
 }
+
+// AsErrorDetails returns the error details within the error chain of err. If err does not contain error details, a 500
+// Internal Server Error is returned instead.
+func AsErrorDetails(err error) ErrorDetails {
+	var e ErrorDetails
+	if errors.As(err, &e) {
+		return e
+	}
+	return NewError(http.StatusInternalServerError)
+}
@@ -1,7 +1,10 @@
 package response
 
 import (
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"net/http"
 	"testing"
 )
 
@@ -36,3 +39,37 @@ func Test_slugify(t *testing.T) {
 		})
 	}
 }
+
+func TestAsErrorDetails(t *testing.T) {
+	details := NewError(http.StatusNotFound).WithMessage("Order not found")
+	tests := []struct {
+		name string
+		err  error
+		want ErrorDetails
+	}{
+		{
+			name: "Error details are returned",
+			err:  details,
+			want: details,
+		},
+		{
+			name: "Wrapped error details are returned",
+			err:  fmt.Errorf("wrapped: %w", details),
+			want: details,
+		},
+		{
+			name: "Other errors return internal server error",
+			err:  errors.New("test error"),
+			want: NewError(http.StatusInternalServerError),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, AsErrorDetails(tt.err), "AsErrorDetails(%v)", tt.err)
+		})
+	}
+}
+
+func TestErrorDetails_Error(t *testing.T) {
+	assert.Equal(t, "404 not_found: Order not found", NewError(http.StatusNotFound).WithMessage("Order not found").Error())
+}
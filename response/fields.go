@@ -0,0 +1,254 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// fieldTree is a set of requested field paths, keyed by the name of each field. A nil subtree includes the whole field.
+type fieldTree map[string]fieldTree
+
+// WithSparseFields prunes the body of a JSON response to the fields requested in the `fields` query parameter of the
+// request, e.g. `?fields=id,name,address.city`. See WithFields.
+func (r Response) WithSparseFields(req *http.Request) Response {
+	fields := req.URL.Query().Get("fields")
+	if fields == "" {
+		return r
+	}
+	return r.WithFields(strings.Split(fields, ",")...)
+}
+
+// WithFields prunes the body of a JSON response to the field paths, using the names of the fields in JSON. Paths to
+// nested fields are separated by ".", and apply to each item of an array. For a page created with NewPage, the paths
+// apply to the items of the page.
+//
+// Only successful responses with a BodyDecoded are pruned, any other response is returned unchanged. If a path
+// includes an unknown field, a 400 Bad Request error response is returned. Fields are known from the json tags of
+// the type of the body, or from the body itself if it is a map or json.RawMessage.
+func (r Response) WithFields(paths ...string) Response {
+	if r.ContentType != contentTypeJson || r.BodyDecoded == nil || r.StatusCode < 200 || r.StatusCode > 299 {
+		return r
+	}
+	tree := parseFieldTree(paths)
+	if len(tree) == 0 {
+		return r
+	}
+
+	body := r.BodyDecoded
+	page, isPage := body.(PageBody)
+	if isPage {
+		body = page.Items
+	}
+
+	data, err := toJsonValue(body)
+	if err != nil {
+		return NewError(http.StatusInternalServerError).JsonResponse()
+	}
+	if unknown := unknownFields(reflect.TypeOf(body), []any{data}, tree, ""); len(unknown) > 0 {
+		sort.Strings(unknown)
+		return NewError(http.StatusBadRequest).
+			WithMessage("Unknown fields requested").
+			WithMeta(map[string]string{"fields": "unknown fields: " + strings.Join(unknown, ", ")}).
+			JsonResponse()
+	}
+
+	c := r.Clone()
+	c.BodyDecoded = pruneFields(data, tree)
+	if isPage {
+		page.Items = c.BodyDecoded
+		c.BodyDecoded = page
+	}
+	return c
+}
+
+func parseFieldTree(paths []string) fieldTree {
+	tree := fieldTree{}
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		node := tree
+		segments := strings.Split(path, ".")
+		for i, segment := range segments {
+			child, exists := node[segment]
+			if i == len(segments)-1 {
+				// Requesting the whole field overrides any nested fields
+				node[segment] = nil
+				break
+			}
+			if exists && child == nil {
+				break
+			}
+			if child == nil {
+				child = fieldTree{}
+				node[segment] = child
+			}
+			node = child
+		}
+	}
+	return tree
+}
+
+// toJsonValue converts a value to the generic value it would be decoded to from JSON, so it can be pruned. Numbers are
+// decoded as json.Number, so large integers such as ids keep their precision.
+func toJsonValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var data any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func pruneFields(v any, tree fieldTree) any {
+	if tree == nil {
+		return v
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		pruned := make(map[string]any, len(tree))
+		for name, subtree := range tree {
+			if value, ok := v[name]; ok {
+				pruned[name] = pruneFields(value, subtree)
+			}
+		}
+		return pruned
+	case []any:
+		pruned := make([]any, len(v))
+		for i, item := range v {
+			pruned[i] = pruneFields(item, tree)
+		}
+		return pruned
+	}
+	return v
+}
+
+// unknownFields returns the paths in the tree that are not fields of the type. If the fields of the type are not
+// known, e.g. it is a map, an interface or implements json.Marshaler, the paths are checked against the data instead.
+func unknownFields(rt reflect.Type, data []any, tree fieldTree, prefix string) []string {
+	for rt != nil && !implementsMarshaler(rt) &&
+		(rt.Kind() == reflect.Pointer || rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array) {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct || implementsMarshaler(rt) {
+		return unknownDataFields(data, tree, prefix)
+	}
+
+	fields := jsonFields(rt)
+	var unknown []string
+	for name, subtree := range tree {
+		ft, ok := fields[name]
+		if !ok {
+			unknown = append(unknown, prefix+name)
+			continue
+		}
+		if subtree != nil {
+			unknown = append(unknown, unknownFields(ft, childData(data, name), subtree, prefix+name+".")...)
+		}
+	}
+	return unknown
+}
+
+func implementsMarshaler(rt reflect.Type) bool {
+	return rt.Implements(jsonMarshalerType) || reflect.PointerTo(rt).Implements(jsonMarshalerType)
+}
+
+// unknownDataFields returns the paths in the tree that are not present in any of the objects in the data. Paths are
+// not checked if there are no values, e.g. an empty array or null.
+func unknownDataFields(data []any, tree fieldTree, prefix string) []string {
+	values := 0
+	for _, v := range flattenArrays(data) {
+		if v != nil {
+			values++
+		}
+	}
+	if values == 0 {
+		return nil
+	}
+
+	var unknown []string
+	for name, subtree := range tree {
+		children := childData(data, name)
+		if len(children) == 0 {
+			unknown = append(unknown, prefix+name)
+			continue
+		}
+		if subtree != nil {
+			unknown = append(unknown, unknownDataFields(children, subtree, prefix+name+".")...)
+		}
+	}
+	return unknown
+}
+
+// childData returns the values of the field in each of the objects in the data, including objects in arrays.
+func childData(data []any, name string) []any {
+	var children []any
+	for _, v := range flattenArrays(data) {
+		if m, ok := v.(map[string]any); ok {
+			if child, exists := m[name]; exists {
+				children = append(children, child)
+			}
+		}
+	}
+	return children
+}
+
+func flattenArrays(data []any) []any {
+	var flat []any
+	for _, v := range data {
+		if a, ok := v.([]any); ok {
+			flat = append(flat, flattenArrays(a)...)
+			continue
+		}
+		flat = append(flat, v)
+	}
+	return flat
+}
+
+// jsonFields returns the types of the fields of a struct keyed by their name in JSON, following the rules of
+// encoding/json for json tags and embedded structs.
+func jsonFields(rt reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := field.Type
+		if field.Anonymous && name == "" {
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for embeddedName, embeddedType := range jsonFields(ft) {
+					if _, exists := fields[embeddedName]; !exists {
+						fields[embeddedName] = embeddedType
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = ft
+	}
+	return fields
+}
@@ -0,0 +1,158 @@
+package response
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fieldsAddress struct {
+	City     string `json:"city"`
+	Postcode string `json:"postcode,omitempty"`
+}
+
+type fieldsAudit struct {
+	CreatedBy string `json:"created_by"`
+}
+
+type fieldsUser struct {
+	fieldsAudit
+	Id        int             `json:"id"`
+	Name      string          `json:"name"`
+	Secret    string          `json:"-"`
+	Address   *fieldsAddress  `json:"address,omitempty"`
+	Previous  []fieldsAddress `json:"previous"`
+	Meta      map[string]any  `json:"meta"`
+	Untagged  string
+	unexposed string
+}
+
+func TestResponse_WithFields(t *testing.T) {
+	user := fieldsUser{
+		fieldsAudit: fieldsAudit{CreatedBy: "admin"},
+		Id:          1,
+		Name:        "Test",
+		Address:     &fieldsAddress{City: "London", Postcode: "N1"},
+		Previous:    []fieldsAddress{{City: "Leeds"}, {City: "York", Postcode: "YO1"}},
+		Meta:        map[string]any{"source": "import", "tags": []any{"a"}},
+		Untagged:    "test",
+	}
+	tests := []struct {
+		name       string
+		resp       Response
+		fields     []string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:     "Top level fields",
+			resp:     NewJson(http.StatusOK, user),
+			fields:   []string{"id", "name", "Untagged", "created_by"},
+			wantBody: `{"created_by":"admin","id":1,"name":"Test","Untagged":"test"}`,
+		},
+		{
+			name:     "Nested fields and arrays",
+			resp:     NewJson(http.StatusOK, []fieldsUser{user, {Id: 2}}),
+			fields:   []string{"id", "address.city", "previous.postcode"},
+			wantBody: `[{"address":{"city":"London"},"id":1,"previous":[{},{"postcode":"YO1"}]},{"id":2,"previous":null}]`,
+		},
+		{
+			name:     "Whole field overrides nested fields",
+			resp:     NewJson(http.StatusOK, &user),
+			fields:   []string{"address.city", "address", " id "},
+			wantBody: `{"address":{"city":"London","postcode":"N1"},"id":1}`,
+		},
+		{
+			name:     "Map fields are checked against the data",
+			resp:     NewJson(http.StatusOK, user),
+			fields:   []string{"meta.source"},
+			wantBody: `{"meta":{"source":"import"}}`,
+		},
+		{
+			name:     "Page items are pruned",
+			resp:     NewPage(httptest.NewRequest(http.MethodGet, "/", nil), Page{Items: []fieldsUser{user}, NextCursor: "a"}),
+			fields:   []string{"id"},
+			wantBody: `{"items":[{"id":1}],"next_cursor":"a"}`,
+		},
+		{
+			name:     "Raw JSON is checked against the data",
+			resp:     NewJson(http.StatusOK, json.RawMessage(`[{"id":1,"name":"a"},{"id":2,"extra":true}]`)),
+			fields:   []string{"id", "extra"},
+			wantBody: `[{"id":1},{"extra":true,"id":2}]`,
+		},
+		{
+			name:     "Empty raw JSON array",
+			resp:     NewJson(http.StatusOK, json.RawMessage(`[]`)),
+			fields:   []string{"id"},
+			wantBody: `[]`,
+		},
+		{
+			name:       "Unknown fields",
+			resp:       NewJson(http.StatusOK, user),
+			fields:     []string{"id", "secret", "unexposed", "address.country", "name.first", "meta.missing"},
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"error":{"status":400,"code":"bad_request","message":"Unknown fields requested","meta":{"fields":` +
+				`"unknown fields: address.country, meta.missing, name.first, secret, unexposed"}}}`,
+		},
+		{
+			name:       "Unknown raw JSON fields",
+			resp:       NewJson(http.StatusOK, json.RawMessage(`{"id":1}`)),
+			fields:     []string{"name"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Error responses are unchanged",
+			resp:       NewJson(http.StatusNotFound, user),
+			fields:     []string{"id"},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:     "No fields",
+			resp:     NewJson(http.StatusOK, fieldsAddress{City: "London"}),
+			fields:   []string{""},
+			wantBody: `{"city":"London"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.resp.WithFields(tt.fields...)
+
+			wantStatus := tt.wantStatus
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			assert.Equal(t, wantStatus, got.StatusCode)
+			if tt.wantBody != "" {
+				body, err := json.Marshal(got.BodyDecoded)
+				assert.NoError(t, err)
+				assert.JSONEq(t, tt.wantBody, string(body))
+			}
+		})
+	}
+}
+
+func TestResponse_WithFields_LargeNumbers(t *testing.T) {
+	// Compared exactly, as assert.JSONEq decodes numbers to float64 and would hide any loss of precision
+	got := NewJson(http.StatusOK, fieldsUser{Id: 1234567890123456789, Name: "Test"}).WithFields("id")
+	body, err := json.Marshal(got.BodyDecoded)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1234567890123456789}`, string(body))
+
+	got = NewJson(http.StatusOK, json.RawMessage(`[{"id":9007199254740993,"amount":0.1}]`)).WithFields("id", "amount")
+	body, err = json.Marshal(got.BodyDecoded)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"amount":0.1,"id":9007199254740993}]`, string(body))
+}
+
+func TestResponse_WithSparseFields(t *testing.T) {
+	resp := NewJson(http.StatusOK, fieldsAddress{City: "London", Postcode: "N1"})
+
+	got := resp.WithSparseFields(httptest.NewRequest(http.MethodGet, "/?fields=city", nil))
+	assert.Equal(t, map[string]any{"city": "London"}, got.BodyDecoded)
+	assert.Equal(t, fieldsAddress{City: "London", Postcode: "N1"}, resp.BodyDecoded, "original is unchanged")
+
+	got = resp.WithSparseFields(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, resp.BodyDecoded, got.BodyDecoded)
+}
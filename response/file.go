@@ -0,0 +1,190 @@
+package response
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dispositionInline = "inline"
+const dispositionAttachment = "attachment"
+
+// sniffLen is the number of bytes used to detect the content type of a file, as used by http.DetectContentType.
+const sniffLen = 512
+
+// NewFile creates a new response serving a file from a file system, to be displayed inline by the client. The content
+// type is detected from the file extension, or by sniffing the content if the extension is not recognised. The
+// Content-Disposition, Content-Length and Last-Modified headers are set from the file.
+//
+// If the file does not exist a 404 Not Found error is returned, or a 403 Forbidden error if permission is denied.
+//
+// Conditional and range requests can be supported by chaining EvaluatePreconditions and WithRange.
+func NewFile(fsys fs.FS, name string) Response {
+	return newFileFromFS(fsys, name, dispositionInline)
+}
+
+// NewAttachment creates a new response serving a file from a file system, to be downloaded and saved by the client.
+// Otherwise, it behaves the same as NewFile.
+func NewAttachment(fsys fs.FS, name string) Response {
+	return newFileFromFS(fsys, name, dispositionAttachment)
+}
+
+// NewFileReader creates a new response serving the content of a file to be displayed inline by the client. The name
+// is used to detect the content type and set the Content-Disposition header, and the modification time is used to set
+// the Last-Modified header if not zero.
+func NewFileReader(name string, modTime time.Time, content io.ReadSeeker) Response {
+	return newFile(name, modTime, content, dispositionInline)
+}
+
+// NewAttachmentReader creates a new response serving the content of a file to be downloaded and saved by the client.
+// Otherwise, it behaves the same as NewFileReader.
+func NewAttachmentReader(name string, modTime time.Time, content io.ReadSeeker) Response {
+	return newFile(name, modTime, content, dispositionAttachment)
+}
+
+func newFileFromFS(fsys fs.FS, name, disposition string) Response {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return fileError(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fileError(err)
+	}
+	if info.IsDir() {
+		_ = f.Close()
+		return fileError(fs.ErrNotExist)
+	}
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		return newFile(name, info.ModTime(), &fileReadSeeker{ReadSeeker: rs, file: f}, disposition)
+	}
+
+	// The file cannot seek, so the content type cannot be sniffed and range requests cannot be supported
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = contentTypeOctetStream
+	}
+	resp := NewReader(http.StatusOK, f).
+		WithContentType(contentType).
+		WithHeader("Content-Disposition", contentDisposition(disposition, path.Base(name))).
+		WithHeader("Content-Length", strconv.FormatInt(info.Size(), 10))
+	if !info.ModTime().IsZero() {
+		resp = resp.WithLastModified(info.ModTime())
+	}
+	return resp
+}
+
+func newFile(name string, modTime time.Time, content io.ReadSeeker, disposition string) Response {
+	contentType, err := detectContentType(name, content)
+	if err != nil {
+		closeReader(content)
+		return NewError(http.StatusInternalServerError).JsonResponse()
+	}
+	size, err := Response{BodyReader: content}.size()
+	if err != nil {
+		closeReader(content)
+		return NewError(http.StatusInternalServerError).JsonResponse()
+	}
+
+	resp := NewReader(http.StatusOK, content).
+		WithContentType(contentType).
+		WithHeader("Content-Disposition", contentDisposition(disposition, path.Base(name))).
+		WithHeader("Content-Length", strconv.FormatInt(size, 10))
+	if !modTime.IsZero() {
+		resp = resp.WithLastModified(modTime)
+	}
+	return resp
+}
+
+// detectContentType detects the content type of a file from the extension of its name, or by sniffing the content if
+// the extension is not recognised.
+func detectContentType(name string, content io.ReadSeeker) (string, error) {
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		return contentType, nil
+	}
+	var buf [sniffLen]byte
+	n, err := io.ReadFull(content, buf[:])
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// contentDisposition formats a Content-Disposition header as described in RFC 6266, with an ASCII fallback filename
+// parameter for older clients and a UTF-8 encoded filename* parameter.
+func contentDisposition(disposition, filename string) string {
+	var fallback strings.Builder
+	ascii := true
+	for _, r := range filename {
+		switch {
+		case r > 0x7e || r < 0x20:
+			ascii = false
+			fallback.WriteRune('_')
+		case r == '"' || r == '\\':
+			fallback.WriteRune('_')
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+
+	header := disposition + `; filename="` + fallback.String() + `"`
+	if !ascii || fallback.String() != filename {
+		header += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+	return header
+}
+
+// encodeExtValue percent-encodes a value for use in an extended header parameter, as described in RFC 8187.
+func encodeExtValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAttrChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+	return b.String()
+}
+
+// isAttrChar reports whether the byte can be used unencoded in an extended header parameter.
+func isAttrChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
+
+func fileError(err error) Response {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return NewError(http.StatusNotFound).JsonResponse()
+	case errors.Is(err, fs.ErrPermission):
+		return NewError(http.StatusForbidden).JsonResponse()
+	default:
+		return NewError(http.StatusInternalServerError).JsonResponse()
+	}
+}
+
+// fileReadSeeker reads and seeks a file opened from a file system, closing the file when closed.
+type fileReadSeeker struct {
+	io.ReadSeeker
+	file fs.File
+}
+
+func (f *fileReadSeeker) Close() error {
+	return f.file.Close()
+}
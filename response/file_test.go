@@ -0,0 +1,258 @@
+package response
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var fileModTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+var testFS = fstest.MapFS{
+	"docs/report.pdf":  {Data: []byte("%PDF-1.4 test"), ModTime: fileModTime},
+	"docs/readme":      {Data: []byte("<html><body>test</body></html>"), ModTime: fileModTime},
+	"docs/data.bin":    {Data: []byte{0x00, 0x01, 0x02}, ModTime: fileModTime},
+	"docs/ünïcode.txt": {Data: []byte("test"), ModTime: fileModTime},
+}
+
+type errorFS struct {
+	err error
+}
+
+func (f errorFS) Open(string) (fs.File, error) {
+	return nil, f.err
+}
+
+// nonSeekableFS wraps a file system so that files opened from it do not implement io.Seeker.
+type nonSeekableFS struct {
+	fs.FS
+}
+
+type nonSeekableFile struct {
+	f fs.File
+}
+
+func (f nonSeekableFile) Stat() (fs.FileInfo, error) { return f.f.Stat() }
+func (f nonSeekableFile) Read(p []byte) (int, error) { return f.f.Read(p) }
+func (f nonSeekableFile) Close() error               { return f.f.Close() }
+
+func (f nonSeekableFS) Open(name string) (fs.File, error) {
+	file, err := f.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return nonSeekableFile{f: file}, nil
+}
+
+func TestNewFile(t *testing.T) {
+	tests := []struct {
+		name                string
+		fsys                fs.FS
+		file                string
+		attachment          bool
+		wantStatus          int
+		wantContentType     string
+		wantDisposition     string
+		wantContentLength   string
+		wantLastModified    string
+		wantBody            string
+		wantRangesSupported bool
+	}{
+		{
+			name:                "Content type detected from extension",
+			fsys:                testFS,
+			file:                "docs/report.pdf",
+			wantStatus:          http.StatusOK,
+			wantContentType:     "application/pdf",
+			wantDisposition:     `inline; filename="report.pdf"`,
+			wantContentLength:   "13",
+			wantLastModified:    "Fri, 01 Mar 2024 12:00:00 GMT",
+			wantBody:            "%PDF-1.4 test",
+			wantRangesSupported: true,
+		},
+		{
+			name:                "Content type sniffed without extension",
+			fsys:                testFS,
+			file:                "docs/readme",
+			wantStatus:          http.StatusOK,
+			wantContentType:     "text/html; charset=utf-8",
+			wantDisposition:     `inline; filename="readme"`,
+			wantContentLength:   "30",
+			wantLastModified:    "Fri, 01 Mar 2024 12:00:00 GMT",
+			wantBody:            "<html><body>test</body></html>",
+			wantRangesSupported: true,
+		},
+		{
+			name:                "Attachment sets disposition",
+			fsys:                testFS,
+			file:                "docs/data.bin",
+			attachment:          true,
+			wantStatus:          http.StatusOK,
+			wantContentType:     "application/octet-stream",
+			wantDisposition:     `attachment; filename="data.bin"`,
+			wantContentLength:   "3",
+			wantLastModified:    "Fri, 01 Mar 2024 12:00:00 GMT",
+			wantBody:            "\x00\x01\x02",
+			wantRangesSupported: true,
+		},
+		{
+			name:                "Non-ASCII filename is encoded",
+			fsys:                testFS,
+			file:                "docs/ünïcode.txt",
+			attachment:          true,
+			wantStatus:          http.StatusOK,
+			wantContentType:     "text/plain; charset=utf-8",
+			wantDisposition:     `attachment; filename="_n_code.txt"; filename*=UTF-8''%C3%BCn%C3%AFcode.txt`,
+			wantContentLength:   "4",
+			wantLastModified:    "Fri, 01 Mar 2024 12:00:00 GMT",
+			wantBody:            "test",
+			wantRangesSupported: true,
+		},
+		{
+			name:              "Non-seekable file is streamed",
+			fsys:              nonSeekableFS{testFS},
+			file:              "docs/readme",
+			wantStatus:        http.StatusOK,
+			wantContentType:   "application/octet-stream",
+			wantDisposition:   `inline; filename="readme"`,
+			wantContentLength: "30",
+			wantLastModified:  "Fri, 01 Mar 2024 12:00:00 GMT",
+			wantBody:          "<html><body>test</body></html>",
+		},
+		{
+			name:            "Missing file returns not found",
+			fsys:            testFS,
+			file:            "docs/missing.pdf",
+			wantStatus:      http.StatusNotFound,
+			wantContentType: contentTypeJson,
+		},
+		{
+			name:            "Directory returns not found",
+			fsys:            testFS,
+			file:            "docs",
+			wantStatus:      http.StatusNotFound,
+			wantContentType: contentTypeJson,
+		},
+		{
+			name:            "Permission denied returns forbidden",
+			fsys:            errorFS{fs.ErrPermission},
+			file:            "docs/report.pdf",
+			wantStatus:      http.StatusForbidden,
+			wantContentType: contentTypeJson,
+		},
+		{
+			name:            "Other error returns internal server error",
+			fsys:            errorFS{errors.New("test error")},
+			file:            "docs/report.pdf",
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: contentTypeJson,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp Response
+			if tt.attachment {
+				resp = NewAttachment(tt.fsys, tt.file)
+			} else {
+				resp = NewFile(tt.fsys, tt.file)
+			}
+
+			w := httptest.NewRecorder()
+			assert.NoError(t, resp.WriteTo(w))
+
+			assert.Equalf(t, tt.wantStatus, w.Code, "status code")
+			assert.Equalf(t, tt.wantContentType, w.Header().Get("Content-Type"), "Content-Type")
+			assert.Equalf(t, tt.wantDisposition, w.Header().Get("Content-Disposition"), "Content-Disposition")
+			assert.Equalf(t, tt.wantContentLength, w.Header().Get("Content-Length"), "Content-Length")
+			assert.Equalf(t, tt.wantLastModified, w.Header().Get("Last-Modified"), "Last-Modified")
+			if tt.wantStatus == http.StatusOK {
+				assert.Equalf(t, tt.wantBody, w.Body.String(), "body")
+			}
+
+			_, seekable := resp.BodyReader.(io.ReadSeeker)
+			assert.Equalf(t, tt.wantRangesSupported, seekable, "seekable")
+		})
+	}
+}
+
+func TestNewFileReader(t *testing.T) {
+	resp := NewAttachmentReader("report.pdf", fileModTime, strings.NewReader("%PDF-1.4 test"))
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, resp.WriteTo(w))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="report.pdf"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "13", w.Header().Get("Content-Length"))
+	assert.Equal(t, "Fri, 01 Mar 2024 12:00:00 GMT", w.Header().Get("Last-Modified"))
+	assert.Equal(t, "%PDF-1.4 test", w.Body.String())
+
+	inline := NewFileReader("readme", time.Time{}, strings.NewReader("plain text"))
+	assert.Equal(t, "text/plain; charset=utf-8", inline.ContentType)
+	assert.Equal(t, `inline; filename="readme"`, inline.Headers.Get("Content-Disposition"))
+	assert.Empty(t, inline.Headers.Get("Last-Modified"))
+}
+
+func TestNewFile_ConditionalRange(t *testing.T) {
+	t.Run("Not modified since last modified", func(t *testing.T) {
+		req := &http.Request{Method: http.MethodGet, Header: http.Header{
+			"If-Modified-Since": {fileModTime.Format(http.TimeFormat)},
+		}}
+		got := NewFile(testFS, "docs/report.pdf").EvaluatePreconditions(req).WithRange(req)
+		assert.Equal(t, http.StatusNotModified, got.StatusCode)
+	})
+
+	t.Run("Range of modified file", func(t *testing.T) {
+		req := &http.Request{Method: http.MethodGet, Header: http.Header{
+			"If-Modified-Since": {fileModTime.Add(-time.Hour).Format(http.TimeFormat)},
+			"Range":             {"bytes=0-3"},
+		}}
+		w := httptest.NewRecorder()
+		assert.NoError(t, NewFile(testFS, "docs/report.pdf").EvaluatePreconditions(req).WithRange(req).WriteTo(w))
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "bytes 0-3/13", w.Header().Get("Content-Range"))
+		assert.Equal(t, "4", w.Header().Get("Content-Length"))
+		assert.Equal(t, "%PDF", w.Body.String())
+	})
+}
+
+func Test_contentDisposition(t *testing.T) {
+	tests := []struct {
+		name        string
+		disposition string
+		filename    string
+		want        string
+	}{
+		{
+			name:        "ASCII filename",
+			disposition: dispositionAttachment,
+			filename:    "report 2024.pdf",
+			want:        `attachment; filename="report 2024.pdf"`,
+		},
+		{
+			name:        "Quotes and backslashes are replaced in fallback",
+			disposition: dispositionInline,
+			filename:    `a"b\c.txt`,
+			want:        `inline; filename="a_b_c.txt"; filename*=UTF-8''a%22b%5Cc.txt`,
+		},
+		{
+			name:        "Non-ASCII filename",
+			disposition: dispositionAttachment,
+			filename:    "€ rates.pdf",
+			want:        `attachment; filename="_ rates.pdf"; filename*=UTF-8''%E2%82%AC%20rates.pdf`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := contentDisposition(tt.disposition, tt.filename)
+			assert.Equalf(t, tt.want, got, "contentDisposition(%v, %v)", tt.disposition, tt.filename)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package response
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Page is a page of items in a list response.
+type Page struct {
+	// Items is the items in the page, encoded as JSON.
+	Items any
+	// Total is the total number of items across all pages, omitted if nil.
+	Total *int
+	// NextCursor is the cursor for the next page, omitted if empty.
+	NextCursor string
+	// PrevCursor is the cursor for the previous page, omitted if empty.
+	PrevCursor string
+	// Links are the query parameters of the related pages, used to build the Link header.
+	Links PageLinks
+}
+
+// PageLinks are the query parameters of the pages related to the current page. Each is applied to the URL of the
+// current request to build the link, so only the parameters that change need to be set. A parameter with an empty
+// value is removed. A link is omitted if its parameters are nil.
+type PageLinks struct {
+	First map[string]string
+	Prev  map[string]string
+	Next  map[string]string
+	Last  map[string]string
+}
+
+// PageBody is the standard envelope of a list response.
+type PageBody struct {
+	Items      any    `json:"items"`
+	Total      *int   `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// NewPage creates a new 200 OK JSON response with the page in the standard envelope, and a Link header (RFC 8288)
+// with the first, prev, next and last pages built from the URL of the request.
+func NewPage(r *http.Request, page Page) Response {
+	resp := NewJson(http.StatusOK, PageBody{
+		Items:      page.Items,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+
+	var links []string
+	for _, l := range []struct {
+		rel    string
+		params map[string]string
+	}{
+		{"first", page.Links.First},
+		{"prev", page.Links.Prev},
+		{"next", page.Links.Next},
+		{"last", page.Links.Last},
+	} {
+		if l.params != nil {
+			links = append(links, "<"+pageURL(r, l.params)+`>; rel="`+l.rel+`"`)
+		}
+	}
+	if len(links) > 0 {
+		resp.Headers.Set("Link", strings.Join(links, ", "))
+	}
+	return resp
+}
+
+// pageURL returns the path and query of the request, with the parameters applied.
+func pageURL(r *http.Request, params map[string]string) string {
+	q := r.URL.Query()
+	for k, v := range params {
+		if v == "" {
+			q.Del(k)
+			continue
+		}
+		q.Set(k, v)
+	}
+	u := *r.URL
+	u.Scheme = ""
+	u.Host = ""
+	u.User = nil
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
@@ -0,0 +1,65 @@
+package response
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewPage(t *testing.T) {
+	total := 42
+	tests := []struct {
+		name     string
+		url      string
+		page     Page
+		wantBody PageBody
+		wantLink string
+	}{
+		{
+			name: "Links are built from the request URL",
+			url:  "http://example.com/items?status=active&limit=10&offset=10",
+			page: Page{
+				Items: []string{"a", "b"},
+				Total: &total,
+				Links: PageLinks{
+					First: map[string]string{"offset": ""},
+					Prev:  map[string]string{"offset": "0"},
+					Next:  map[string]string{"offset": "20"},
+					Last:  map[string]string{"offset": "40"},
+				},
+			},
+			wantBody: PageBody{Items: []string{"a", "b"}, Total: &total},
+			wantLink: `</items?limit=10&status=active>; rel="first", ` +
+				`</items?limit=10&offset=0&status=active>; rel="prev", ` +
+				`</items?limit=10&offset=20&status=active>; rel="next", ` +
+				`</items?limit=10&offset=40&status=active>; rel="last"`,
+		},
+		{
+			name: "Nil links are omitted",
+			url:  "/items?cursor=abc",
+			page: Page{
+				Items:      []string{"a"},
+				NextCursor: "def",
+				Links:      PageLinks{Next: map[string]string{"cursor": "def"}},
+			},
+			wantBody: PageBody{Items: []string{"a"}, NextCursor: "def"},
+			wantLink: `</items?cursor=def>; rel="next"`,
+		},
+		{
+			name:     "No links",
+			url:      "/items",
+			page:     Page{Items: []string{}},
+			wantBody: PageBody{Items: []string{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewPage(httptest.NewRequest(http.MethodGet, tt.url, nil), tt.page)
+			assert.Equal(t, http.StatusOK, got.StatusCode)
+			assert.Equal(t, contentTypeJson, got.ContentType)
+			assert.Equal(t, tt.wantBody, got.BodyDecoded)
+			assert.Equal(t, tt.wantLink, got.Headers.Get("Link"))
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package response
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errBodyReader = errors.New("body is streamed from a reader")
+var errRangeMalformed = errors.New("range is malformed")
+var errRangeUnsatisfiable = errors.New("range is not satisfiable")
+
+type byteRange struct {
+	start, length int64
+}
+
+func (br byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", br.start, br.start+br.length-1, size)
+}
+
+// WithRange applies the Range header of the request to the response, as described in RFC 9110 section 14. This is
+// supported for 200 OK responses with an in-memory body, or a body streamed from a reader that implements io.Seeker.
+//
+// Supported responses advertise range support with the Accept-Ranges header. A single satisfiable range returns a 206
+// Partial Content response with the Content-Range header set, and multiple ranges return a multipart/byteranges body.
+// If none of the ranges can be satisfied a 416 Range Not Satisfiable error is returned. If the If-Range header of the
+// request does not match the ETag or Last-Modified header of the response, the full response is returned.
+func (r Response) WithRange(req *http.Request) Response {
+	if r.StatusCode != http.StatusOK {
+		return r
+	}
+	size, err := r.size()
+	if err != nil {
+		return r
+	}
+
+	c := r.Clone()
+	c.Headers.Set("Accept-Ranges", "bytes")
+
+	header := req.Header.Get("Range")
+	if header == "" || req.Method != http.MethodGet {
+		return c
+	}
+	if ifRange := req.Header.Get("If-Range"); ifRange != "" && !c.matchIfRange(ifRange) {
+		return c
+	}
+
+	ranges, err := parseRange(header, size)
+	switch {
+	case errors.Is(err, errRangeUnsatisfiable):
+		closeReader(c.BodyReader)
+		return NewError(http.StatusRequestedRangeNotSatisfiable).
+			JsonResponse().
+			WithHeader("Content-Range", fmt.Sprintf("bytes */%d", size))
+	case err != nil:
+		// Malformed ranges are ignored, as permitted by RFC 9110 section 14.2
+		return c
+	}
+
+	var total int64
+	for _, br := range ranges {
+		total += br.length
+	}
+	if total > size {
+		// The ranges are too inefficient to serve, so send the full response instead
+		return c
+	}
+
+	if len(ranges) == 1 {
+		return c.partial(ranges[0], size)
+	}
+	return c.multipart(ranges, size)
+}
+
+// partial returns a 206 Partial Content response containing a single range of the body.
+func (r Response) partial(br byteRange, size int64) Response {
+	c := r.Clone()
+	c.StatusCode = http.StatusPartialContent
+	c.Headers.Set("Content-Range", br.contentRange(size))
+	c.Headers.Set("Content-Length", strconv.FormatInt(br.length, 10))
+	if rs, ok := r.BodyReader.(io.ReadSeeker); ok {
+		c.BodyReader = &bodyReadCloser{Reader: &rangeReader{rs: rs, br: br}, body: rs}
+		return c
+	}
+	b, _ := r.body()
+	c.BodyEncoded = b[br.start : br.start+br.length]
+	c.BodyDecoded = nil
+	return c
+}
+
+// multipart returns a 206 Partial Content response containing multiple ranges of the body, as a multipart/byteranges
+// body described in RFC 9110 section 14.6.
+func (r Response) multipart(ranges []byteRange, size int64) Response {
+	boundary := randomBoundary()
+	contentType := r.Headers.Get("Content-Type")
+	if contentType == "" {
+		contentType = r.ContentType
+	}
+
+	var parts []io.Reader
+	var length int64
+	for i, br := range ranges {
+		var h bytes.Buffer
+		if i > 0 {
+			h.WriteString("\r\n")
+		}
+		h.WriteString("--" + boundary + "\r\n")
+		if contentType != "" {
+			h.WriteString("Content-Type: " + contentType + "\r\n")
+		}
+		h.WriteString("Content-Range: " + br.contentRange(size) + "\r\n\r\n")
+		parts = append(parts, bytes.NewReader(h.Bytes()))
+		length += int64(h.Len()) + br.length
+
+		if rs, ok := r.BodyReader.(io.ReadSeeker); ok {
+			parts = append(parts, &rangeReader{rs: rs, br: br})
+			continue
+		}
+		b, _ := r.body()
+		parts = append(parts, bytes.NewReader(b[br.start:br.start+br.length]))
+	}
+	closing := "\r\n--" + boundary + "--\r\n"
+	parts = append(parts, strings.NewReader(closing))
+	length += int64(len(closing))
+
+	c := r.Clone()
+	c.StatusCode = http.StatusPartialContent
+	c.ContentType = "multipart/byteranges; boundary=" + boundary
+	c.Headers.Del("Content-Type")
+	c.Headers.Set("Content-Length", strconv.FormatInt(length, 10))
+	c.BodyReader = &bodyReadCloser{Reader: io.MultiReader(parts...), body: r.BodyReader}
+	c.BodyDecoded = nil
+	c.BodyEncoded = nil
+	return c
+}
+
+// matchIfRange reports whether the If-Range header matches the validators of the response. An entity tag must match
+// the ETag header using strong comparison, and a date must exactly match the Last-Modified header.
+func (r Response) matchIfRange(ifRange string) bool {
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return matchETag(ifRange, r.Headers.Get("ETag"), false)
+	}
+	t, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	lm, err := http.ParseTime(r.Headers.Get("Last-Modified"))
+	return err == nil && lm.Equal(t.Truncate(time.Second))
+}
+
+// size returns the size of the body. For a body streamed from a reader, the reader must implement io.Seeker.
+func (r Response) size() (int64, error) {
+	if r.BodyReader == nil {
+		b, err := r.body()
+		return int64(len(b)), err
+	}
+	rs, ok := r.BodyReader.(io.ReadSeeker)
+	if !ok {
+		return 0, errBodyReader
+	}
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// parseRange parses a Range header into the byte ranges it represents within a body of the given size. Ranges that do
+// not overlap the body are ignored, and if none overlap errRangeUnsatisfiable is returned.
+func parseRange(header string, size int64) ([]byteRange, error) {
+	unit, spec, ok := strings.Cut(header, "=")
+	if !ok || strings.TrimSpace(unit) != "bytes" {
+		return nil, errRangeMalformed
+	}
+
+	var ranges []byteRange
+	for _, part := range strings.Split(spec, ",") {
+		part = textproto.TrimString(part)
+		if part == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, errRangeMalformed
+		}
+		first, last = textproto.TrimString(first), textproto.TrimString(last)
+
+		var br byteRange
+		if first == "" {
+			// Suffix range, the final n bytes
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errRangeMalformed
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			n = min(n, size)
+			br = byteRange{start: size - n, length: n}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, errRangeMalformed
+			}
+			end := size - 1
+			if last != "" {
+				if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+					return nil, errRangeMalformed
+				}
+			}
+			if start >= size {
+				continue
+			}
+			end = min(end, size-1)
+			br = byteRange{start: start, length: end - start + 1}
+		}
+		ranges = append(ranges, br)
+	}
+	if len(ranges) == 0 {
+		return nil, errRangeUnsatisfiable
+	}
+	return ranges, nil
+}
+
+// rangeReader reads a range of a seekable body, seeking to the start of the range on the first read.
+type rangeReader struct {
+	rs     io.ReadSeeker
+	br     byteRange
+	read   int64
+	seeked bool
+}
+
+func (rr *rangeReader) Read(p []byte) (int, error) {
+	if !rr.seeked {
+		if _, err := rr.rs.Seek(rr.br.start, io.SeekStart); err != nil {
+			return 0, err
+		}
+		rr.seeked = true
+	}
+	remaining := rr.br.length - rr.read
+	if remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := rr.rs.Read(p)
+	rr.read += int64(n)
+	if errors.Is(err, io.EOF) && rr.read < rr.br.length {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// bodyReadCloser reads from a reader derived from the body of a response, closing the original body when closed.
+type bodyReadCloser struct {
+	io.Reader
+	body io.Reader
+}
+
+func (b *bodyReadCloser) Close() error {
+	if closer, ok := b.body.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// closeReader closes the body reader of a response that will not be written.
+func closeReader(body io.Reader) {
+	if closer, ok := body.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+func randomBoundary() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,273 @@
+package response
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		size    int64
+		want    []byteRange
+		wantErr error
+	}{
+		{
+			name:   "Single range",
+			header: "bytes=0-4",
+			size:   10,
+			want:   []byteRange{{start: 0, length: 5}},
+		},
+		{
+			name:   "Open ended range",
+			header: "bytes=5-",
+			size:   10,
+			want:   []byteRange{{start: 5, length: 5}},
+		},
+		{
+			name:   "Suffix range",
+			header: "bytes=-3",
+			size:   10,
+			want:   []byteRange{{start: 7, length: 3}},
+		},
+		{
+			name:   "Suffix range larger than body",
+			header: "bytes=-30",
+			size:   10,
+			want:   []byteRange{{start: 0, length: 10}},
+		},
+		{
+			name:   "End beyond body is truncated",
+			header: "bytes=8-100",
+			size:   10,
+			want:   []byteRange{{start: 8, length: 2}},
+		},
+		{
+			name:   "Multiple ranges with whitespace",
+			header: "bytes=0-1, 4-5 ,-1",
+			size:   10,
+			want:   []byteRange{{start: 0, length: 2}, {start: 4, length: 2}, {start: 9, length: 1}},
+		},
+		{
+			name:   "Ranges beyond body are ignored",
+			header: "bytes=0-1,20-30",
+			size:   10,
+			want:   []byteRange{{start: 0, length: 2}},
+		},
+		{
+			name:    "All ranges beyond body is unsatisfiable",
+			header:  "bytes=20-30",
+			size:    10,
+			wantErr: errRangeUnsatisfiable,
+		},
+		{
+			name:    "Empty body is unsatisfiable",
+			header:  "bytes=-5",
+			size:    0,
+			wantErr: errRangeUnsatisfiable,
+		},
+		{
+			name:    "Unknown unit is malformed",
+			header:  "items=0-5",
+			size:    10,
+			wantErr: errRangeMalformed,
+		},
+		{
+			name:    "End before start is malformed",
+			header:  "bytes=5-1",
+			size:    10,
+			wantErr: errRangeMalformed,
+		},
+		{
+			name:    "Non-numeric range is malformed",
+			header:  "bytes=a-b",
+			size:    10,
+			wantErr: errRangeMalformed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRange(tt.header, tt.size)
+			assert.ErrorIsf(t, err, tt.wantErr, "parseRange(%v, %v)", tt.header, tt.size)
+			assert.Equalf(t, tt.want, got, "parseRange(%v, %v)", tt.header, tt.size)
+		})
+	}
+}
+
+func TestResponse_WithRange(t *testing.T) {
+	const body = "0123456789"
+	lastModified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	sources := map[string]func() Response{
+		"in-memory": func() Response {
+			return New(http.StatusOK, []byte(body)).WithETag("abc").WithLastModified(lastModified)
+		},
+		"reader": func() Response {
+			return NewReader(http.StatusOK, strings.NewReader(body)).
+				WithContentType(contentTypePlainText).
+				WithETag("abc").
+				WithLastModified(lastModified)
+		},
+	}
+
+	tests := []struct {
+		name              string
+		method            string
+		header            http.Header
+		wantStatus        int
+		wantContentRange  string
+		wantContentLength string
+		wantBody          string
+	}{
+		{
+			name:       "No range returns full response",
+			header:     http.Header{},
+			wantStatus: http.StatusOK,
+			wantBody:   body,
+		},
+		{
+			name:              "Single range returns partial content",
+			header:            http.Header{"Range": {"bytes=2-5"}},
+			wantStatus:        http.StatusPartialContent,
+			wantContentRange:  "bytes 2-5/10",
+			wantContentLength: "4",
+			wantBody:          "2345",
+		},
+		{
+			name:             "Unsatisfiable range returns error",
+			header:           http.Header{"Range": {"bytes=20-"}},
+			wantStatus:       http.StatusRequestedRangeNotSatisfiable,
+			wantContentRange: "bytes */10",
+		},
+		{
+			name:       "Malformed range returns full response",
+			header:     http.Header{"Range": {"bytes=x"}},
+			wantStatus: http.StatusOK,
+			wantBody:   body,
+		},
+		{
+			name:       "Range on non-GET request returns full response",
+			method:     http.MethodPost,
+			header:     http.Header{"Range": {"bytes=2-5"}},
+			wantStatus: http.StatusOK,
+			wantBody:   body,
+		},
+		{
+			name:       "Ranges larger than body return full response",
+			header:     http.Header{"Range": {"bytes=0-8,1-9"}},
+			wantStatus: http.StatusOK,
+			wantBody:   body,
+		},
+		{
+			name:              "Matching If-Range etag returns partial content",
+			header:            http.Header{"Range": {"bytes=0-0"}, "If-Range": {`"abc"`}},
+			wantStatus:        http.StatusPartialContent,
+			wantContentRange:  "bytes 0-0/10",
+			wantContentLength: "1",
+			wantBody:          "0",
+		},
+		{
+			name:       "Mismatched If-Range etag returns full response",
+			header:     http.Header{"Range": {"bytes=0-0"}, "If-Range": {`"xyz"`}},
+			wantStatus: http.StatusOK,
+			wantBody:   body,
+		},
+		{
+			name:              "Matching If-Range date returns partial content",
+			header:            http.Header{"Range": {"bytes=-1"}, "If-Range": {lastModified.Format(http.TimeFormat)}},
+			wantStatus:        http.StatusPartialContent,
+			wantContentRange:  "bytes 9-9/10",
+			wantContentLength: "1",
+			wantBody:          "9",
+		},
+		{
+			name:       "Mismatched If-Range date returns full response",
+			header:     http.Header{"Range": {"bytes=-1"}, "If-Range": {lastModified.Add(-time.Hour).Format(http.TimeFormat)}},
+			wantStatus: http.StatusOK,
+			wantBody:   body,
+		},
+	}
+	for source, newResponse := range sources {
+		for _, tt := range tests {
+			t.Run(source+": "+tt.name, func(t *testing.T) {
+				method := tt.method
+				if method == "" {
+					method = http.MethodGet
+				}
+				req := &http.Request{Method: method, Header: tt.header}
+
+				w := httptest.NewRecorder()
+				assert.NoError(t, newResponse().WithRange(req).WriteTo(w))
+
+				assert.Equalf(t, tt.wantStatus, w.Code, "status code")
+				assert.Equalf(t, tt.wantContentRange, w.Header().Get("Content-Range"), "Content-Range")
+				assert.Equalf(t, tt.wantContentLength, w.Header().Get("Content-Length"), "Content-Length")
+				if tt.wantStatus != http.StatusRequestedRangeNotSatisfiable {
+					assert.Equalf(t, "bytes", w.Header().Get("Accept-Ranges"), "Accept-Ranges")
+					assert.Equalf(t, tt.wantBody, w.Body.String(), "body")
+				}
+			})
+		}
+	}
+}
+
+func TestResponse_WithRange_Multipart(t *testing.T) {
+	const body = "0123456789"
+	for source, resp := range map[string]Response{
+		"in-memory": New(http.StatusOK, []byte(body)),
+		"reader":    NewReader(http.StatusOK, strings.NewReader(body)).WithContentType(contentTypePlainText),
+	} {
+		t.Run(source, func(t *testing.T) {
+			req := &http.Request{Method: http.MethodGet, Header: http.Header{"Range": {"bytes=0-1,-2"}}}
+
+			w := httptest.NewRecorder()
+			assert.NoError(t, resp.WithRange(req).WriteTo(w))
+			assert.Equal(t, http.StatusPartialContent, w.Code)
+			assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+
+			mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+			assert.NoError(t, err)
+			assert.Equal(t, "multipart/byteranges", mediaType)
+
+			mr := multipart.NewReader(w.Body, params["boundary"])
+			for _, want := range []struct{ contentRange, body string }{
+				{"bytes 0-1/10", "01"},
+				{"bytes 8-9/10", "89"},
+			} {
+				part, err := mr.NextPart()
+				if !assert.NoError(t, err) {
+					return
+				}
+				b, _ := io.ReadAll(part)
+				assert.Equal(t, contentTypePlainText, part.Header.Get("Content-Type"))
+				assert.Equal(t, want.contentRange, part.Header.Get("Content-Range"))
+				assert.Equal(t, want.body, string(b))
+			}
+			_, err = mr.NextPart()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestResponse_WithRange_Unsupported(t *testing.T) {
+	t.Run("Non-OK response is unchanged", func(t *testing.T) {
+		resp := New(http.StatusNotFound, []byte("test"))
+		req := &http.Request{Method: http.MethodGet, Header: http.Header{"Range": {"bytes=0-1"}}}
+		assert.Equal(t, resp, resp.WithRange(req))
+	})
+
+	t.Run("Non-seekable reader is unchanged", func(t *testing.T) {
+		resp := NewReader(http.StatusOK, io.MultiReader(strings.NewReader("test")))
+		req := &http.Request{Method: http.MethodGet, Header: http.Header{"Range": {"bytes=0-1"}}}
+		assert.Equal(t, resp, resp.WithRange(req))
+	})
+}
@@ -2,16 +2,19 @@ package response
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 )
 
 const contentTypePlainText = "text/plain"
 const contentTypeJson = "application/json"
+const contentTypeOctetStream = "application/octet-stream"
 
 type Response struct {
 	StatusCode  int
 	BodyEncoded []byte
 	BodyDecoded any
+	BodyReader  io.Reader
 	ContentType string
 	Headers     http.Header
 }
@@ -36,6 +39,18 @@ func NewJson(statusCode int, body any) Response {
 	}
 }
 
+// NewReader creates a new binary response, streaming the body from a reader when written. If the reader implements
+// io.Closer it will be closed once written. If the reader implements io.Seeker, range requests can be supported with
+// WithRange.
+func NewReader(statusCode int, body io.Reader) Response {
+	return Response{
+		StatusCode:  statusCode,
+		BodyReader:  body,
+		ContentType: contentTypeOctetStream,
+		Headers:     map[string][]string{},
+	}
+}
+
 // NewNoContent creates a new response with no body.
 func NewNoContent(statusCode int) Response {
 	return Response{
@@ -49,6 +64,7 @@ func (r Response) Clone() Response {
 		StatusCode:  r.StatusCode,
 		BodyEncoded: r.BodyEncoded,
 		BodyDecoded: r.BodyDecoded,
+		BodyReader:  r.BodyReader,
 		ContentType: r.ContentType,
 		Headers:     r.Headers.Clone(),
 	}
@@ -90,6 +106,17 @@ func (r Response) WriteTo(w http.ResponseWriter) error {
 		}
 	}
 
+	if r.BodyReader != nil {
+		// Stream
+		_, err := io.Copy(w, r.BodyReader)
+		if closer, ok := r.BodyReader.(io.Closer); ok {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}
+
 	if len(r.BodyEncoded) > 0 {
 		// Plain
 		_, err := w.Write(r.BodyEncoded)
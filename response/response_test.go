@@ -4,7 +4,9 @@ import (
 	"errors"
 	"github.com/ellogroup/ello-golang-http/internal/mock"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -17,6 +19,7 @@ func TestResponse_WriteTo(t *testing.T) {
 		statusCode  int
 		bodyEncoded []byte
 		bodyDecoded any
+		bodyReader  io.Reader
 		contentType string
 		headers     http.Header
 	}
@@ -151,6 +154,28 @@ func TestResponse_WriteTo(t *testing.T) {
 			wantBody:   []byte(`test-123`),
 			wantErr:    assert.NoError,
 		},
+		{
+			name: "Reader payload written when set",
+			fields: fields{
+				statusCode:  200,
+				bodyReader:  strings.NewReader("test-123"),
+				bodyEncoded: []byte("not-written"),
+			},
+			wantHeader: map[string][]string{},
+			wantBody:   []byte("test-123"),
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "Write error when reader payload written returns error",
+			fields: fields{
+				statusCode: 200,
+				bodyReader: strings.NewReader("test-123"),
+			},
+			writeBodyError: errors.New("write error"),
+			wantHeader:     map[string][]string{},
+			wantBody:       []byte("test-123"),
+			wantErr:        assert.Error,
+		},
 		{
 			name: "Write error when encoded payload written returns error",
 			fields: fields{
@@ -169,6 +194,7 @@ func TestResponse_WriteTo(t *testing.T) {
 				StatusCode:  tt.fields.statusCode,
 				BodyEncoded: tt.fields.bodyEncoded,
 				BodyDecoded: tt.fields.bodyDecoded,
+				BodyReader:  tt.fields.bodyReader,
 				ContentType: tt.fields.contentType,
 				Headers:     tt.fields.headers,
 			}
@@ -177,7 +203,7 @@ func TestResponse_WriteTo(t *testing.T) {
 			headers := http.Header(map[string][]string{})
 			writerMock.On("Header").Return(headers).Maybe()
 			writerMock.On("WriteHeader", tt.fields.statusCode).Once()
-			writerMock.On("Write", tt.wantBody).Return(1, tt.writeBodyError).Maybe()
+			writerMock.On("Write", tt.wantBody).Return(len(tt.wantBody), tt.writeBodyError).Maybe()
 
 			if !tt.wantErr(t, r.WriteTo(writerMock), "WriteTo()") {
 				return
@@ -189,3 +215,26 @@ func TestResponse_WriteTo(t *testing.T) {
 		})
 	}
 }
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestResponse_WriteTo_ClosesReader(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("test-123")}
+
+	writerMock := new(mock.ResponseWriter)
+	writerMock.On("Header").Return(http.Header{})
+	writerMock.On("WriteHeader", http.StatusOK).Once()
+	writerMock.On("Write", []byte("test-123")).Return(8, nil).Once()
+
+	assert.NoError(t, NewReader(http.StatusOK, body).WriteTo(writerMock))
+	assert.True(t, body.closed, "closed")
+	writerMock.AssertExpectations(t)
+}